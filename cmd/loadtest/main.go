@@ -0,0 +1,197 @@
+// Command loadtest 模擬熱門短網址的快取失效瞬間（cache stampede）：
+// 每一輪先刪除 Redis 的 url:<code> 並廣播失效讓各副本清掉行程內快取，
+// 再讓所有 worker 同時打 GET /<code>，最後比較伺服器回報的 PostgreSQL 載入次數。
+//
+// 以 CACHE_COALESCE_LOADS=false 與 true 各啟動一次伺服器執行，即可看出 singleflight 的差異：
+//
+//	go run ./cmd/loadtest -code 0000g8 -concurrency 200 -rounds 5 -redis localhost:6379 -admin-user admin -admin-pass secret
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+type result struct {
+	status  int
+	latency time.Duration
+	err     error
+}
+
+func main() {
+	baseURL := flag.String("base", "http://localhost:8080", "service base URL")
+	code := flag.String("code", "", "short code to hit (required)")
+	concurrency := flag.Int("concurrency", 200, "concurrent requests per round")
+	rounds := flag.Int("rounds", 5, "number of rounds")
+	redisAddr := flag.String("redis", "", "Redis address; when set, url:<code> is evicted before every round")
+	redisPassword := flag.String("redis-password", "", "Redis password")
+	adminUser := flag.String("admin-user", "", "Basic Auth user for /api/v1/admin/cache/stats")
+	adminPass := flag.String("admin-pass", "", "Basic Auth password for /api/v1/admin/cache/stats")
+	flag.Parse()
+
+	if *code == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var rdb *redis.Client
+	if *redisAddr != "" {
+		rdb = redis.NewClient(&redis.Options{Addr: *redisAddr, Password: *redisPassword})
+		defer rdb.Close()
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		// 只量測短網址服務本身，不跟隨重定向
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		Transport: &http.Transport{
+			MaxIdleConns:        *concurrency,
+			MaxIdleConnsPerHost: *concurrency,
+		},
+	}
+
+	before, statsErr := fetchStats(client, *baseURL, *adminUser, *adminPass)
+	if statsErr != nil {
+		log.Printf("cache stats unavailable, DB load counts will not be reported: %v", statsErr)
+	}
+
+	var all []result
+	for round := 1; round <= *rounds; round++ {
+		if rdb != nil {
+			if err := evict(rdb, *code); err != nil {
+				log.Fatalf("evict failed: %v", err)
+			}
+		}
+
+		results := burst(client, *baseURL+"/"+*code, *concurrency)
+		all = append(all, results...)
+		fmt.Printf("round %d: %s\n", round, summarize(results))
+	}
+	fmt.Printf("total:   %s\n", summarize(all))
+
+	if statsErr == nil {
+		after, err := fetchStats(client, *baseURL, *adminUser, *adminPass)
+		if err != nil {
+			log.Fatalf("cache stats failed: %v", err)
+		}
+		fmt.Printf("db loads: %d, coalesced requests: %d, lock wait hits: %d\n",
+			after.Loads.DBLoads-before.Loads.DBLoads,
+			after.Loads.Coalesced-before.Loads.Coalesced,
+			after.Loads.LockWaitHits-before.Loads.LockWaitHits)
+		if *redisAddr != "" {
+			fmt.Println("(counts are for the replica that served /api/v1/admin/cache/stats; run against a single replica for exact numbers)")
+		}
+	}
+}
+
+// evict 模擬 url:<code> 過期：刪除 Redis key，並透過 url:invalidate 讓所有副本清除行程內 LRU
+func evict(rdb *redis.Client, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := rdb.Del(ctx, "url:"+code).Err(); err != nil {
+		return err
+	}
+	return rdb.Publish(ctx, "url:invalidate", "loadtest:"+code).Err()
+}
+
+// burst 讓 n 個 worker 在同一瞬間送出請求
+func burst(client *http.Client, target string, n int) []result {
+	results := make([]result, n)
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+
+			begin := time.Now()
+			resp, err := client.Get(target)
+			results[i].latency = time.Since(begin)
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			resp.Body.Close()
+			results[i].status = resp.StatusCode
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+	return results
+}
+
+func summarize(results []result) string {
+	statuses := make(map[int]int)
+	errors := 0
+	latencies := make([]time.Duration, 0, len(results))
+	for _, r := range results {
+		if r.err != nil {
+			errors++
+			continue
+		}
+		statuses[r.status]++
+		latencies = append(latencies, r.latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	return fmt.Sprintf("requests=%d errors=%d statuses=%v p50=%v p95=%v p99=%v max=%v",
+		len(results), errors, statuses,
+		percentile(latencies, 0.50), percentile(latencies, 0.95), percentile(latencies, 0.99), percentile(latencies, 1))
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i].Round(time.Microsecond)
+}
+
+func fetchStats(client *http.Client, baseURL, user, pass string) (*model.CacheStats, error) {
+	if user == "" {
+		return nil, fmt.Errorf("no admin credentials")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/api/v1/admin/cache/stats", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(user, pass)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var stats model.CacheStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -0,0 +1,49 @@
+// Command rehash 依目前的 URL_DEDUP_MODE 與 URL_CANON_* 重算所有短網址的 url_hash 與 canonical_url。
+//
+// 007_canonical_url.sql 之前建立的短網址以原始字串計算去重雜湊，canonical 模式下相同目的地的新請求找不到它們、
+// 會建立重複的短碼。套用 007 之後（以及切換 URL_DEDUP_MODE、修改 URL_CANON_* 之後）執行一次；服務運作中也可以執行。
+// 新雜湊已屬於另一筆短網址時保留舊值並列為 conflicts。
+//
+// 連線設定與伺服器相同（讀取 .env / 環境變數）。-dry-run 只列出需要更新的筆數。
+//
+//	go run ./cmd/rehash -dry-run
+//	go run ./cmd/rehash -batch 1000
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/jack/golang-short-url-service/internal/config"
+	"github.com/jack/golang-short-url-service/internal/repository"
+	"github.com/jack/golang-short-url-service/internal/service"
+)
+
+func main() {
+	batch := flag.Int("batch", 500, "links read per query")
+	dryRun := flag.Bool("dry-run", false, "count the links that would change without writing")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.URL.DedupMode == service.DedupModeOff {
+		log.Fatalf("URL_DEDUP_MODE=off does not deduplicate, nothing to rehash")
+	}
+
+	postgresRepo, err := repository.NewPostgresRepository(&cfg.Postgres)
+	if err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer postgresRepo.Close()
+
+	stats, err := service.RehashURLs(context.Background(), postgresRepo, &cfg.URL, max(*batch, 1), *dryRun)
+	if err != nil {
+		log.Fatalf("rehash failed after %d links: %v", stats.Scanned, err)
+	}
+	fmt.Printf("mode=%s dry_run=%v scanned=%d updated=%d conflicts=%d skipped=%d\n",
+		cfg.URL.DedupMode, *dryRun, stats.Scanned, stats.Updated, stats.Conflicts, stats.Skipped)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jack/golang-short-url-service/internal/config"
+	"github.com/jack/golang-short-url-service/internal/handler"
+)
+
+// SetupAdmin 配置管理 API 路由（檢舉審核、快取統計與預熱、leader 與背景 job、限流狀態）（與 Swagger UI 共用 Basic Auth 帳密）
+func SetupAdmin(router *gin.Engine, auth *config.AuthConfig, h *handler.Handler) {
+	// 如果沒有設置認證，就禁用管理 API
+	if auth.BasicUser == "" || auth.BasicPassword == "" {
+		router.Any("/api/v1/admin/*any", func(c *gin.Context) {
+			c.JSON(403, gin.H{
+				"error":   "forbidden",
+				"message": "Admin API is disabled. Set AUTH_BASIC_USER and AUTH_BASIC_PASSWORD to enable.",
+			})
+		})
+		return
+	}
+
+	admin := router.Group("/api/v1/admin", gin.BasicAuth(gin.Accounts{
+		auth.BasicUser: auth.BasicPassword,
+	}))
+
+	admin.GET("/reports", h.ListReports)
+	admin.GET("/reports/:id", h.GetReport)
+	admin.POST("/reports/:id/disable", h.DisableReport)
+	admin.POST("/reports/:id/dismiss", h.DismissReport)
+
+	admin.GET("/cache/stats", h.CacheStats)
+	admin.POST("/cache/warm", h.WarmCache)
+	admin.GET("/cache/warm", h.WarmCacheStatus)
+
+	admin.GET("/leader", h.LeaderStatus)
+	admin.GET("/jobs", h.ListJobs)
+	admin.POST("/jobs/:name/run", h.RunJob)
+
+	admin.GET("/ratelimit", h.RateLimitStatus)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jack/golang-short-url-service/internal/config"
+	"github.com/jack/golang-short-url-service/internal/leader"
+	"github.com/jack/golang-short-url-service/internal/repository"
+	"github.com/jack/golang-short-url-service/internal/scheduler"
+	"github.com/jack/golang-short-url-service/internal/service"
+)
+
+// SetupJobs 註冊背景 job（排程由 JOB_<NAME>_SCHEDULE/JITTER/TIMEOUT 設定）
+func SetupJobs(
+	cfg *config.Config,
+	postgresRepo *repository.PostgresRepository,
+	redisRepo *repository.RedisRepository,
+	shortURLService *service.ShortURLService,
+	elector *leader.Elector,
+) (*scheduler.Scheduler, error) {
+	jobs := scheduler.New()
+
+	clickSync := scheduler.NewClickSync(postgresRepo, redisRepo, elector, cfg.Clicks.SyncBatch)
+	if err := registerJob(jobs, "click-sync", cfg.Jobs.ClickSync, scheduler.Job{
+		Leader:    elector,
+		RunOnStop: true,
+		Run:       clickSync.Run,
+	}); err != nil {
+		return nil, err
+	}
+
+	// 封存過期短網址；SKIP LOCKED 讓多副本同時執行也不會重複封存，仍只由 leader 執行以免互搶同一批
+	if err := registerJob(jobs, "url-purge", cfg.Jobs.URLPurge, scheduler.Job{
+		Leader: elector,
+		Run: func(ctx context.Context) error {
+			_, err := shortURLService.PurgeExpiredURLs(ctx)
+			return err
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	// 新 leader 上任時立即同步一次，前任留下的 clicks:pending:* 不必等到下一次排程
+	elector.OnElected(func() {
+		_, _ = jobs.Trigger("click-sync")
+	})
+
+	return jobs, nil
+}
+
+func registerJob(jobs *scheduler.Scheduler, name string, cfg config.JobConfig, job scheduler.Job) error {
+	schedule, err := scheduler.ParseSchedule(cfg.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	job.Name = name
+	job.Schedule = schedule
+	job.Jitter = cfg.Jitter
+	job.Timeout = cfg.Timeout
+	return jobs.Register(job)
+}
@@ -12,17 +12,15 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/jack/golang-short-url-service/internal/config"
+	"github.com/jack/golang-short-url-service/internal/geoip"
 	"github.com/jack/golang-short-url-service/internal/handler"
+	"github.com/jack/golang-short-url-service/internal/leader"
 	"github.com/jack/golang-short-url-service/internal/middleware"
+	"github.com/jack/golang-short-url-service/internal/policy"
 	"github.com/jack/golang-short-url-service/internal/repository"
-	"github.com/jack/golang-short-url-service/internal/scheduler"
 	"github.com/jack/golang-short-url-service/internal/service"
 )
 
-const (
-	ClickSyncInterval = 1 * time.Hour
-)
-
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
@@ -40,30 +38,84 @@ func main() {
 	defer postgresRepo.Close()
 	log.Println("Connected to PostgreSQL")
 
-	redisRepo, err := repository.NewRedisRepository(&cfg.Redis)
+	redisRepo, err := repository.NewRedisRepository(&cfg.Redis, &cfg.Cache)
 	if err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 	defer redisRepo.Close()
-	log.Println("Connected to Redis")
+	if redisRepo.Available() {
+		log.Println("Connected to Redis")
+	} else {
+		log.Println("Redis unavailable, serving from PostgreSQL until it recovers")
+	}
 
-	clickSyncScheduler := scheduler.NewClickSyncScheduler(postgresRepo, redisRepo, ClickSyncInterval)
-	clickSyncScheduler.Start()
-	defer clickSyncScheduler.Stop()
+	policyEngine, err := policy.NewEngine(&cfg.Policy)
+	if err != nil {
+		log.Fatalf("Failed to load URL policy: %v", err)
+	}
 
-	shortURLService := service.NewShortURLService(postgresRepo, redisRepo, cfg)
+	shortURLService := service.NewShortURLService(postgresRepo, redisRepo, policyEngine, cfg)
 
-	h := handler.NewHandler(shortURLService)
+	// 多副本時只有 leader 執行點擊同步等 leader 限定的 job；
+	// 關閉時先停掉點擊緩衝（defer 反序），再做最後一次同步（jobs Stop），最後交出 lease（elector Stop）
+	elector := leader.New(redisRepo, "click-sync", &cfg.Leader)
+	jobs, err := SetupJobs(cfg, postgresRepo, redisRepo, shortURLService, elector)
+	if err != nil {
+		log.Fatalf("Failed to set up jobs: %v", err)
+	}
+	elector.Start()
+	defer elector.Stop()
+	jobs.Start()
+	defer jobs.Stop()
+
+	shortURLService.StartCodeFilter()
+	defer shortURLService.StopCodeFilter()
+	shortURLService.StartClickBuffer()
+	defer shortURLService.StopClickBuffer()
+
+	// Redis failover 後快取是冷的，可在啟動時預熱（CACHE_WARM_ON_START=top|all）
+	if mode := cfg.Cache.WarmOnStart; mode != "" && mode != "off" {
+		if _, err := shortURLService.StartCacheWarm(mode, 0); err != nil {
+			log.Printf("cache warm on start failed: mode=%s err=%v", mode, err)
+		}
+	}
+	defer shortURLService.StopCacheWarm()
 
-	// 一般 API 限流（使用配置文件設定）
-	rateLimiter := middleware.NewRateLimiter(redisRepo.Client(), &cfg.RateLimit)
+	// 規則變更（啟動、threat list 重新載入）後，停用命中新規則的既有短網址
+	sweepBlockedLinks := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
 
-	// 創建短網址的嚴格限流（10次/分鐘）
-	strictRateLimitConfig := &config.RateLimitConfig{
-		Requests: 10,
-		Duration: time.Minute,
+		count, err := shortURLService.DeactivateBlockedLinks(ctx)
+		if err != nil {
+			log.Printf("policy sweep failed: deactivated=%d err=%v", count, err)
+			return
+		}
+		if count > 0 {
+			log.Printf("policy sweep deactivated %d links", count)
+		}
+	}
+	policyEngine.OnReload(sweepBlockedLinks)
+	policyEngine.Start()
+	defer policyEngine.Stop()
+	go sweepBlockedLinks()
+
+	// GeoIP 為選配：未設定 GEOIP_DB_PATH 時 geoResolver 為 nil，geo 規則一律走預設目的地。
+	var geoResolver *geoip.Resolver
+	if cfg.GeoIP.DBPath != "" {
+		geoResolver, err = geoip.Open(cfg.GeoIP.DBPath)
+		if err != nil {
+			log.Fatalf("Failed to open GeoIP database: %v", err)
+		}
+		defer geoResolver.Close()
+		log.Printf("Loaded GeoIP database: %s", cfg.GeoIP.DBPath)
 	}
-	strictRateLimiter := middleware.NewRateLimiter(redisRepo.Client(), strictRateLimitConfig)
+
+	// 限流：依 RATE_LIMIT_KEY_BY 辨識呼叫者（API key、租戶、IP），依方案套用各路由的限流與每日／每月配額；
+	// Redis 出錯時各路由依 RATE_LIMIT_FAILURE_MODE(S) 放行、拒絕或改用行程內限流
+	rateLimiter := middleware.NewRateLimiter(redisRepo.Client(), &cfg.RateLimit)
+
+	h := handler.NewHandler(shortURLService, geoResolver, elector, jobs, rateLimiter)
 
 	router := gin.New()
 
@@ -89,14 +141,28 @@ func main() {
 
 	api := router.Group("/api/v1")
 	{
-		// 創建短網址 - 嚴格限流（10次/分鐘）
-		api.POST("/shorten", strictRateLimiter.Middleware(), h.CreateShortURL)
+		// 創建短網址 - create 限流（free 方案 10次/分鐘），計入配額
+		api.POST("/shorten", rateLimiter.Route("create"), h.CreateShortURL)
+		// 續期（等同以相同目的地重新建立）- 與創建共用 create 限流
+		api.POST("/urls/:code/renew", rateLimiter.Route("create"), h.RenewURL)
 		// 統計查詢 - 一般限流
-		api.GET("/stats/:code", rateLimiter.Middleware(), h.GetStats)
+		api.GET("/stats/:code", rateLimiter.Route("stats"), h.GetStats)
+		// QR code（含 ETag 快取）- 一般限流
+		api.GET("/urls/:code/qr", rateLimiter.Route("qr"), h.QRCode)
+		// 檢舉 - 獨立的 report 限流（free 方案 5次/小時）
+		api.POST("/report/:code", rateLimiter.Route("report"), h.ReportAbuse)
+		// 呼叫者的方案與配額用量 - 一般限流
+		api.GET("/usage", rateLimiter.Route("usage"), h.Usage)
 	}
 
+	// 管理 API（檢舉審核）
+	SetupAdmin(router, &cfg.Auth, h)
+
+	// 預覽頁（不計點擊）- 一般限流；/:code+ 由 Redirect 轉交
+	router.GET("/preview/:code", rateLimiter.Route("preview"), h.Preview)
+
 	// 重定向 - 一般限流
-	router.GET("/:code", rateLimiter.Middleware(), h.Redirect)
+	router.GET("/:code", rateLimiter.Route("redirect"), h.Redirect)
 
 	srv := &http.Server{
 		Addr:         ":8080",
@@ -0,0 +1,173 @@
+// Command syncbench 比較點擊同步的兩種做法：
+// 舊做法逐 key GETDEL + 單筆 UPDATE（每個 key 兩次往返），
+// 新做法 ClickSync.Sync（每批 SCAN + 一次 Lua 腳本 + 一個 UPDATE … FROM unnest 交易）。
+//
+// 連線設定與伺服器相同（讀取 .env / 環境變數）。-seed 會建立 n 筆 short_code 為 bench<i> 的測試網址，
+// 兩輪各自在 Redis 寫入同樣的 clicks:<code> 計數後執行並計時；-cleanup 在結束後刪除測試網址。
+//
+//	go run ./cmd/syncbench -n 20000 -batch 1000 -seed -cleanup
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/config"
+	"github.com/jack/golang-short-url-service/internal/repository"
+	"github.com/jack/golang-short-url-service/internal/scheduler"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+const benchPrefix = "bench"
+
+func main() {
+	n := flag.Int("n", 10000, "number of links (clicks:<code> counters) per run")
+	clicks := flag.Int64("clicks", 3, "clicks per counter")
+	batch := flag.Int("batch", 1000, "keys per chunk for the batched sync")
+	seed := flag.Bool("seed", false, "insert the bench<i> test links before running")
+	cleanup := flag.Bool("cleanup", false, "delete the bench<i> test links afterwards")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, cfg.Postgres.DSN())
+	if err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer pool.Close()
+
+	postgresRepo, err := repository.NewPostgresRepository(&cfg.Postgres)
+	if err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer postgresRepo.Close()
+
+	redisRepo, err := repository.NewRedisRepository(&cfg.Redis, &cfg.Cache)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer redisRepo.Close()
+	if !redisRepo.Available() {
+		log.Fatalf("Redis unavailable")
+	}
+
+	if *seed {
+		if err := seedLinks(ctx, pool, *n); err != nil {
+			log.Fatalf("seed failed: %v", err)
+		}
+	}
+	if *cleanup {
+		defer func() {
+			if err := cleanupLinks(ctx, pool); err != nil {
+				log.Printf("cleanup failed: %v", err)
+			}
+		}()
+	}
+
+	codes := make([]string, *n)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("%s%d", benchPrefix, i+1)
+	}
+
+	// 舊做法
+	if err := seedCounters(ctx, redisRepo, codes, *clicks); err != nil {
+		log.Fatalf("seed counters failed: %v", err)
+	}
+	start := time.Now()
+	legacyKeys, err := legacySync(ctx, postgresRepo, redisRepo)
+	if err != nil {
+		log.Fatalf("legacy sync failed: %v", err)
+	}
+	legacy := time.Since(start)
+	legacyRate := float64(legacyKeys) / legacy.Seconds()
+	fmt.Printf("per-key: keys=%d elapsed=%s (%.0f keys/s)\n", legacyKeys, legacy.Round(time.Millisecond), legacyRate)
+
+	// 新做法
+	if err := seedCounters(ctx, redisRepo, codes, *clicks); err != nil {
+		log.Fatalf("seed counters failed: %v", err)
+	}
+	stats, err := scheduler.NewClickSync(postgresRepo, redisRepo, nil, *batch).Sync(ctx)
+	if err != nil {
+		log.Fatalf("batched sync failed: %v", err)
+	}
+	fmt.Printf("batched: keys=%d chunks=%d replayed=%d elapsed=%s (%.0f keys/s)\n",
+		stats.Keys, stats.Chunks, stats.Replayed, stats.Elapsed.Round(time.Millisecond), stats.KeysPerSecond())
+
+	if legacyRate > 0 {
+		fmt.Printf("speedup: %.1fx\n", stats.KeysPerSecond()/legacyRate)
+	}
+}
+
+// legacySync 是改版前點擊同步的做法：列出所有 key，逐一 GETDEL 後各自 UPDATE
+func legacySync(ctx context.Context, postgresRepo *repository.PostgresRepository, redisRepo *repository.RedisRepository) (int, error) {
+	client := redisRepo.Client()
+	keys, err := legacyClickKeys(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+
+	synced := 0
+	for _, key := range keys {
+		k := repository.ParseClickCountKey(key)
+		count, err := client.GetDel(ctx, key).Int64()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return synced, fmt.Errorf("failed to get and reset %s: %w", key, err)
+		}
+		if count == 0 {
+			continue
+		}
+		if err := postgresRepo.IncrementClickCountBy(ctx, k.ShortCode, count); err != nil {
+			return synced, err
+		}
+		synced++
+	}
+	return synced, nil
+}
+
+// legacyClickKeys 一次 SCAN 出所有 clicks:* 計數器（略過 clicks:pending:* 暫存 hash）
+func legacyClickKeys(ctx context.Context, client *redis.Client) ([]string, error) {
+	var keys []string
+	iter := client.ScanType(ctx, 0, "clicks:*", 0, "string").Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan click count keys: %w", err)
+	}
+	return keys, nil
+}
+
+func seedCounters(ctx context.Context, redisRepo *repository.RedisRepository, codes []string, clicks int64) error {
+	pipe := redisRepo.Client().Pipeline()
+	for _, code := range codes {
+		pipe.IncrBy(ctx, "clicks:"+code, clicks)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func seedLinks(ctx context.Context, pool *pgxpool.Pool, n int) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO urls (short_code, url_hash, original_url, canonical_url)
+		SELECT $1 || g, md5($1 || g), 'https://example.com/' || $1 || '/' || g, 'https://example.com/' || $1 || '/' || g
+		FROM generate_series(1, $2::int) AS g
+		ON CONFLICT DO NOTHING`, benchPrefix, n)
+	return err
+}
+
+func cleanupLinks(ctx context.Context, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(ctx, `DELETE FROM urls WHERE short_code ~ ('^' || $1 || '[0-9]+$')`, benchPrefix)
+	return err
+}
@@ -0,0 +1,206 @@
+// Package breaker implements a consecutive-failure circuit breaker.
+//
+// Closed：正常放行，連續失敗 threshold 次後轉為 Open。
+// Open：直接拒絕（ErrOpen），不再付出逾時的代價；經過 cooldown 後轉為 HalfOpen。
+// HalfOpen：只放行一個探測請求，成功則回到 Closed，失敗則重新 Open。
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOpen 表示斷路器開啟中，請求未被執行
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// Stats is a snapshot of a breaker
+type Stats struct {
+	Name  string
+	State State
+	// Failures is the current run of consecutive failures
+	Failures int
+	// Rejected counts calls short-circuited while open since process start
+	Rejected int64
+	// OpenedAt is zero while closed
+	OpenedAt time.Time
+}
+
+type Breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+	onChange func(from, to State)
+
+	rejected atomic.Int64
+}
+
+// New 建立斷路器；threshold <= 0 時視為 1
+func New(name string, threshold int, cooldown time.Duration) *Breaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &Breaker{name: name, threshold: threshold, cooldown: cooldown}
+}
+
+// OnStateChange 註冊狀態變化的 callback；callback 在獨立 goroutine 執行，可以安全地再呼叫斷路器
+func (b *Breaker) OnStateChange(fn func(from, to State)) {
+	b.mu.Lock()
+	b.onChange = fn
+	b.mu.Unlock()
+}
+
+// Allow 回傳 nil 表示可以執行；之後必須呼叫 Success 或 Failure 回報結果
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		if time.Since(b.openedAt) < b.cooldown {
+			b.rejected.Add(1)
+			return ErrOpen
+		}
+		b.setState(HalfOpen)
+		b.probing = true
+		return nil
+	case HalfOpen:
+		if b.probing {
+			b.rejected.Add(1)
+			return ErrOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	if b.state != Closed {
+		b.setState(Closed)
+	}
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	switch b.state {
+	case HalfOpen:
+		b.trip()
+	case Closed:
+		if b.failures >= b.threshold {
+			b.trip()
+		}
+	}
+}
+
+// Trip 強制開啟斷路器（例如啟動時就連不上）
+func (b *Breaker) Trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != Open {
+		b.trip()
+	}
+}
+
+// DoContext 在斷路器允許時執行 fn；isFailure 決定哪些錯誤算失敗（nil 時所有非 nil 錯誤都算）。
+// fn 失敗時呼叫端的 ctx 已結束（用戶端斷線、呼叫端自己的逾時）則不計成功也不計失敗：
+// 那是呼叫端放棄等待，不代表依賴的狀態；半開時放掉探測名額，讓下一個請求重新探測
+func (b *Breaker) DoContext(ctx context.Context, fn func() error, isFailure func(error) bool) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+
+	err := fn()
+	switch {
+	case err != nil && ctx.Err() != nil:
+		b.release()
+	case err != nil && (isFailure == nil || isFailure(err)):
+		b.Failure()
+	default:
+		b.Success()
+	}
+	return err
+}
+
+// release 結束一次不計結果的呼叫：只放掉探測名額，不改變狀態與連續失敗次數
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Breaker) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := Stats{
+		Name:     b.name,
+		State:    b.state,
+		Failures: b.failures,
+		Rejected: b.rejected.Load(),
+	}
+	if b.state != Closed {
+		stats.OpenedAt = b.openedAt
+	}
+	return stats
+}
+
+func (b *Breaker) trip() {
+	b.openedAt = time.Now()
+	b.setState(Open)
+}
+
+// setState 必須在持有 mu 時呼叫
+func (b *Breaker) setState(to State) {
+	from := b.state
+	b.state = to
+	if b.onChange != nil && from != to {
+		go b.onChange(from, to)
+	}
+}
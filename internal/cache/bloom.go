@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// Bloom is a concurrency-safe Bloom filter for strings; it never returns a false negative
+type Bloom struct {
+	mu    sync.RWMutex
+	bits  []uint64
+	m     uint64 // number of bits
+	k     uint64 // number of hash functions
+	count int    // items added
+}
+
+// NewBloom sizes a filter for capacity items at the given false-positive rate
+func NewBloom(capacity int, fpRate float64) *Bloom {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &Bloom{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// Add inserts s into the filter
+func (b *Bloom) Add(s string) {
+	h1, h2 := bloomHashes(s)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+	b.count++
+}
+
+// MayContain reports whether s may have been added; false means it definitely was not
+func (b *Bloom) MayContain(s string) bool {
+	h1, h2 := bloomHashes(s)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of Add calls (duplicates included)
+func (b *Bloom) Count() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.count
+}
+
+// bloomHashes derives the two base hashes for double hashing (Kirsch–Mitzenmacher)
+func bloomHashes(s string) (uint64, uint64) {
+	a := fnv.New64a()
+	a.Write([]byte(s))
+
+	b := fnv.New64()
+	b.Write([]byte(s))
+
+	// h2 必須是奇數，否則 m 為偶數時 i*h2 只會落在部分位元
+	return a.Sum64(), b.Sum64() | 1
+}
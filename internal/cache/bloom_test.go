@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestBloomNoFalseNegatives(t *testing.T) {
+	tests := []struct {
+		capacity int
+		fpRate   float64
+		added    int
+	}{
+		{10000, 0.01, 10000},
+		{1000, 0.001, 1000},
+		// 超過容量時誤判率上升，但仍不會漏判
+		{100, 0.01, 5000},
+		{0, 0, 50},
+		{1, 1.5, 50},
+	}
+
+	for _, tt := range tests {
+		b := NewBloom(tt.capacity, tt.fpRate)
+		for i := 0; i < tt.added; i++ {
+			b.Add(strconv.Itoa(i))
+		}
+		for i := 0; i < tt.added; i++ {
+			if !b.MayContain(strconv.Itoa(i)) {
+				t.Fatalf("capacity=%d fpRate=%v: MayContain(%d) = false after Add", tt.capacity, tt.fpRate, i)
+			}
+		}
+		if b.Count() != tt.added {
+			t.Errorf("capacity=%d fpRate=%v: Count() = %d, want %d", tt.capacity, tt.fpRate, b.Count(), tt.added)
+		}
+	}
+}
+
+func TestBloomFalsePositiveRate(t *testing.T) {
+	const n = 10000
+	b := NewBloom(n, 0.01)
+	for i := 0; i < n; i++ {
+		b.Add("code-" + strconv.Itoa(i))
+	}
+
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if b.MayContain("absent-" + strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+	// 目標 1%；留足夠的餘裕避免偶發失敗
+	if rate := float64(falsePositives) / n; rate > 0.03 {
+		t.Errorf("false positive rate = %.4f, want about 0.01", rate)
+	}
+}
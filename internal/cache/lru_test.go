@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEviction(t *testing.T) {
+	c := NewLRU[int](2)
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+
+	// 讀取 a 讓 b 成為最久未使用，加入 c 時被擠掉
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v, want 1, true", v, ok)
+	}
+	c.Set("c", 3, time.Minute)
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Get(b) hit, want evicted")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if v, ok := c.Get(key); !ok || v != want {
+			t.Errorf("Get(%s) = %d, %v, want %d, true", key, v, ok, want)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+
+	// 覆寫既有 key 不會擠掉其他項目，並成為最近使用
+	c.Set("a", 10, time.Minute)
+	c.Set("d", 4, time.Minute)
+	if v, ok := c.Get("a"); !ok || v != 10 {
+		t.Errorf("Get(a) = %d, %v, want 10, true", v, ok)
+	}
+	if _, ok := c.Get("c"); ok {
+		t.Errorf("Get(c) hit, want evicted")
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	c := NewLRU[string](10)
+	c.Set("short", "x", 20*time.Millisecond)
+	c.Set("long", "y", time.Minute)
+	c.Set("zero", "z", 0)
+	c.Set("negative", "z", -time.Second)
+
+	if _, ok := c.Get("short"); !ok {
+		t.Fatalf("Get(short) missed before expiry")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2 (non-positive ttl is not stored)", c.Len())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get("short"); ok {
+		t.Errorf("Get(short) hit after expiry")
+	}
+	if _, ok := c.Get("long"); !ok {
+		t.Errorf("Get(long) missed before expiry")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1 (expired entry removed on read)", c.Len())
+	}
+
+	// 重新寫入會延長有效期
+	c.Set("short", "x2", time.Minute)
+	if v, ok := c.Get("short"); !ok || v != "x2" {
+		t.Errorf("Get(short) = %q, %v, want x2, true", v, ok)
+	}
+}
+
+func TestLRUDeleteAndStats(t *testing.T) {
+	c := NewLRU[int](10)
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+
+	c.Delete("a")
+	c.Delete("missing")
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Get(a) hit after Delete")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Errorf("Get(b) missed")
+	}
+
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d after Purge, want 0", c.Len())
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Get(b) hit after Purge")
+	}
+
+	if hits, misses := c.Stats(); hits != 1 || misses != 2 {
+		t.Errorf("Stats() = %d hits, %d misses, want 1, 2", hits, misses)
+	}
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -11,9 +13,15 @@ type Config struct {
 	App       AppConfig
 	Postgres  PostgresConfig
 	Redis     RedisConfig
+	Cache     CacheConfig
+	Clicks    ClickConfig
+	Leader    LeaderConfig
+	Jobs      JobsConfig
 	RateLimit RateLimitConfig
 	URL       URLConfig
 	Auth      AuthConfig
+	GeoIP     GeoIPConfig
+	Policy    PolicyConfig
 }
 
 type AppConfig struct {
@@ -30,6 +38,15 @@ type PostgresConfig struct {
 	SSLMode  string
 	MaxConns int
 	MinConns int
+
+	// 讀取路徑（GetURLByShortCode）與寫入路徑（CreateURL、IncrementClickCountBy）各自的逾時、併發上限與斷路器
+	ReadTimeout      time.Duration
+	WriteTimeout     time.Duration
+	ReadConcurrency  int
+	WriteConcurrency int
+	BulkheadWait     time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
 }
 
 type RedisConfig struct {
@@ -38,16 +55,150 @@ type RedisConfig struct {
 	Password string
 	DB       int
 	PoolSize int
+
+	DialTimeout time.Duration
+	// Timeout 是單一指令的讀寫逾時
+	Timeout          time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+type CacheConfig struct {
+	LocalSize     int
+	LocalTTL      time.Duration
+	CodeFilter    bool
+	BloomCapacity int
+	BloomFPRate   float64
+	BloomRefresh  time.Duration
+	NegativeSize  int
+	NegativeTTL   time.Duration
+	CoalesceLoads bool
+	LoadLock      bool
+	LoadLockWait  time.Duration
+	WarmOnStart   string
+	WarmTopN      int
+	WarmWindow    time.Duration
+	WarmRate      int
+}
+
+// ClickConfig 控制 Redis 無法使用時的點擊緩衝與點擊同步
+type ClickConfig struct {
+	BufferSize  int
+	BufferFlush time.Duration
+	// SyncBatch 是點擊同步每批從 Redis 取出並寫入 PostgreSQL 的 key 數
+	SyncBatch int
+}
+
+// LeaderConfig 控制跨副本的 leader 選舉（同一時間只有 leader 執行點擊同步）
+type LeaderConfig struct {
+	// ID 是本副本在 lease 中的識別（預設為 hostname，Kubernetes 上即 Pod 名稱）
+	ID       string
+	LeaseTTL time.Duration
+}
+
+// JobConfig 是一個背景 job 的排程設定
+type JobConfig struct {
+	// Schedule 是 Go duration（固定間隔）、5 欄 cron（UTC）或 off（只能手動觸發）
+	Schedule string
+	Jitter   time.Duration
+	Timeout  time.Duration
+}
+
+type JobsConfig struct {
+	ClickSync JobConfig
+	URLPurge  JobConfig
 }
 
 type RateLimitConfig struct {
+	// Requests／Duration／Burst 是方案沒有設定 default 路由時的預設額度
 	Requests int
 	Duration time.Duration
+	// Algorithm 是 sliding_window（預設）或 gcra
+	Algorithm string
+	// Burst 是 gcra 可以一次通過的請求數（0 表示等於 Requests）
+	Burst int
+
+	// KeyBy 是依序嘗試的限流對象：api_key、tenant、ip、route，第一個取得到的為準
+	KeyBy []string
+	// DefaultPlan 套用於沒有帶 API key 的請求
+	DefaultPlan string
+	Plans       map[string]PlanConfig
+	APIKeys     []APIKeyConfig
+	// QuotaRoutes 是計入每日／每月配額的路由
+	QuotaRoutes []string
+
+	// FailureMode 是 Redis 出錯時路由的預設處理：open（放行）、closed（回 503）或 local（改用行程內限流）
+	FailureMode string
+	// FailureModes 依路由名稱覆寫 FailureMode
+	FailureModes map[string]string
+	// Replicas 是副本數；local 模式下每個副本的額度為方案額度除以 Replicas
+	Replicas int
+	// LocalSize 是行程內限流最多記錄的限流對象數
+	LocalSize int
+}
+
+// Redis 出錯時的限流處理（RATE_LIMIT_FAILURE_MODE）
+const (
+	FailureModeOpen   = "open"
+	FailureModeClosed = "closed"
+	FailureModeLocal  = "local"
+)
+
+// PlanConfig 是一個方案（PLAN_<NAME>_*）的各路由限流與配額
+type PlanConfig struct {
+	Name string
+	// Limits 以路由名稱（create、report、stats…）為 key；default 套用於沒有列出的路由，且這些路由共用同一份額度
+	Limits map[string]LimitConfig
+	// DailyQuota／MonthlyQuota 是 QuotaRoutes 每日／每月（UTC）的請求上限，0 表示不限
+	DailyQuota   int
+	MonthlyQuota int
+	// MaxExpiry 取代 URL_MAX_EXPIRY 作為這個方案建立／續期短網址的最長有效期，0 表示沿用 URL_MAX_EXPIRY
+	MaxExpiry time.Duration
+}
+
+// LimitConfig 是一條路由的限流：Duration 內 Requests 次
+type LimitConfig struct {
+	Requests int
+	Duration time.Duration
+	// Burst 只用於 gcra，0 表示等於 Requests
+	Burst int
+}
+
+// APIKeyConfig 是一把 API key 所屬的租戶與方案（API_KEYS 的一項：<key>:<tenant>:<plan>）
+type APIKeyConfig struct {
+	Key    string
+	Tenant string
+	Plan   string
+}
+
+// Limit 回傳路由適用的限流與額度名稱：有個別設定時為路由名稱，否則為 default
+func (p PlanConfig) Limit(route string) (string, LimitConfig) {
+	if limit, ok := p.Limits[route]; ok {
+		return route, limit
+	}
+	return defaultRoute, p.Limits[defaultRoute]
 }
 
 type URLConfig struct {
-	DefaultExpiry   time.Duration
-	ShortCodeLength int
+	// DefaultExpiry 用於沒有指定 expires_in／expires_at 的短網址（0 表示不過期）
+	DefaultExpiry time.Duration
+	// MaxExpiry 是短網址的最長有效期（0 表示不限）；設定時每個短網址都會過期
+	MaxExpiry          time.Duration
+	ShortCodeLength    int
+	SelfLinkMode       string
+	SelfHosts          []string
+	KnownShorteners    []string
+	MaxRedirectDepth   int
+	DedupMode          string
+	CanonSortQuery     bool
+	CanonStripTracking bool
+
+	// PurgeGrace 是過期多久之後由 url-purge job 封存到 urls_archive
+	PurgeGrace time.Duration
+	// PurgeBatch 是 url-purge 每批封存的筆數
+	PurgeBatch int
+	// PurgeCodes 決定封存後的短碼：tombstone（保留，GET 回 410）或 release（釋出給之後建立的短網址接手，GET 回 404）
+	PurgeCodes string
 }
 
 type AuthConfig struct {
@@ -55,6 +206,19 @@ type AuthConfig struct {
 	BasicPassword string
 }
 
+type GeoIPConfig struct {
+	DBPath string
+}
+
+type PolicyConfig struct {
+	Blocklist        []string
+	Allowlist        []string
+	ThreatListFile   string
+	ReloadInterval   time.Duration
+	RejectIPLiterals bool
+	RejectPrivate    bool
+}
+
 func Load() (*Config, error) {
 	viper.SetConfigName(".env")
 	viper.SetConfigType("env")
@@ -81,6 +245,14 @@ func Load() (*Config, error) {
 			SSLMode:  viper.GetString("POSTGRES_SSLMODE"),
 			MaxConns: viper.GetInt("POSTGRES_MAX_CONNS"),
 			MinConns: viper.GetInt("POSTGRES_MIN_CONNS"),
+
+			ReadTimeout:      viper.GetDuration("POSTGRES_READ_TIMEOUT"),
+			WriteTimeout:     viper.GetDuration("POSTGRES_WRITE_TIMEOUT"),
+			ReadConcurrency:  viper.GetInt("POSTGRES_READ_CONCURRENCY"),
+			WriteConcurrency: viper.GetInt("POSTGRES_WRITE_CONCURRENCY"),
+			BulkheadWait:     viper.GetDuration("POSTGRES_BULKHEAD_WAIT"),
+			BreakerThreshold: viper.GetInt("POSTGRES_BREAKER_THRESHOLD"),
+			BreakerCooldown:  viper.GetDuration("POSTGRES_BREAKER_COOLDOWN"),
 		},
 		Redis: RedisConfig{
 			Host:     viper.GetString("REDIS_HOST"),
@@ -88,24 +260,282 @@ func Load() (*Config, error) {
 			Password: viper.GetString("REDIS_PASSWORD"),
 			DB:       viper.GetInt("REDIS_DB"),
 			PoolSize: viper.GetInt("REDIS_POOL_SIZE"),
+
+			DialTimeout:      viper.GetDuration("REDIS_DIAL_TIMEOUT"),
+			Timeout:          viper.GetDuration("REDIS_TIMEOUT"),
+			BreakerThreshold: viper.GetInt("REDIS_BREAKER_THRESHOLD"),
+			BreakerCooldown:  viper.GetDuration("REDIS_BREAKER_COOLDOWN"),
+		},
+		Cache: CacheConfig{
+			LocalSize:     viper.GetInt("CACHE_LOCAL_SIZE"),
+			LocalTTL:      viper.GetDuration("CACHE_LOCAL_TTL"),
+			CodeFilter:    viper.GetBool("CACHE_CODE_FILTER"),
+			BloomCapacity: viper.GetInt("CACHE_BLOOM_CAPACITY"),
+			BloomFPRate:   viper.GetFloat64("CACHE_BLOOM_FP_RATE"),
+			BloomRefresh:  viper.GetDuration("CACHE_BLOOM_REFRESH"),
+			NegativeSize:  viper.GetInt("CACHE_NEGATIVE_SIZE"),
+			NegativeTTL:   viper.GetDuration("CACHE_NEGATIVE_TTL"),
+			CoalesceLoads: viper.GetBool("CACHE_COALESCE_LOADS"),
+			LoadLock:      viper.GetBool("CACHE_LOAD_LOCK"),
+			LoadLockWait:  viper.GetDuration("CACHE_LOAD_LOCK_WAIT"),
+			WarmOnStart:   viper.GetString("CACHE_WARM_ON_START"),
+			WarmTopN:      viper.GetInt("CACHE_WARM_TOP_N"),
+			WarmWindow:    viper.GetDuration("CACHE_WARM_WINDOW"),
+			WarmRate:      viper.GetInt("CACHE_WARM_RATE"),
+		},
+		Clicks: ClickConfig{
+			BufferSize:  viper.GetInt("CLICK_BUFFER_SIZE"),
+			BufferFlush: viper.GetDuration("CLICK_BUFFER_FLUSH"),
+			SyncBatch:   viper.GetInt("CLICK_SYNC_BATCH"),
+		},
+		Leader: LeaderConfig{
+			ID:       viper.GetString("LEADER_ID"),
+			LeaseTTL: viper.GetDuration("LEADER_LEASE_TTL"),
+		},
+		Jobs: JobsConfig{
+			ClickSync: loadJobConfig("JOB_CLICK_SYNC"),
+			URLPurge:  loadJobConfig("JOB_URL_PURGE"),
 		},
 		RateLimit: RateLimitConfig{
-			Requests: viper.GetInt("RATE_LIMIT_REQUESTS"),
-			Duration: viper.GetDuration("RATE_LIMIT_DURATION"),
+			Requests:  viper.GetInt("RATE_LIMIT_REQUESTS"),
+			Duration:  viper.GetDuration("RATE_LIMIT_DURATION"),
+			Algorithm: viper.GetString("RATE_LIMIT_ALGORITHM"),
+			Burst:     viper.GetInt("RATE_LIMIT_BURST"),
+
+			KeyBy:       splitList(viper.GetString("RATE_LIMIT_KEY_BY")),
+			DefaultPlan: viper.GetString("RATE_LIMIT_DEFAULT_PLAN"),
+			QuotaRoutes: splitList(viper.GetString("RATE_LIMIT_QUOTA_ROUTES")),
+
+			FailureMode: viper.GetString("RATE_LIMIT_FAILURE_MODE"),
+			Replicas:    viper.GetInt("RATE_LIMIT_REPLICAS"),
+			LocalSize:   viper.GetInt("RATE_LIMIT_LOCAL_SIZE"),
 		},
 		URL: URLConfig{
-			DefaultExpiry:   viper.GetDuration("URL_DEFAULT_EXPIRY"),
-			ShortCodeLength: viper.GetInt("SHORT_CODE_LENGTH"),
+			DefaultExpiry:      viper.GetDuration("URL_DEFAULT_EXPIRY"),
+			MaxExpiry:          viper.GetDuration("URL_MAX_EXPIRY"),
+			ShortCodeLength:    viper.GetInt("SHORT_CODE_LENGTH"),
+			SelfLinkMode:       viper.GetString("URL_SELF_LINK_MODE"),
+			SelfHosts:          splitList(viper.GetString("URL_SELF_HOSTS")),
+			KnownShorteners:    splitList(viper.GetString("URL_KNOWN_SHORTENERS")),
+			MaxRedirectDepth:   viper.GetInt("URL_MAX_REDIRECT_DEPTH"),
+			DedupMode:          viper.GetString("URL_DEDUP_MODE"),
+			CanonSortQuery:     viper.GetBool("URL_CANON_SORT_QUERY"),
+			CanonStripTracking: viper.GetBool("URL_CANON_STRIP_TRACKING"),
+			PurgeGrace:         viper.GetDuration("URL_PURGE_GRACE"),
+			PurgeBatch:         viper.GetInt("URL_PURGE_BATCH"),
+			PurgeCodes:         viper.GetString("URL_PURGE_CODES"),
 		},
 		Auth: AuthConfig{
 			BasicUser:     viper.GetString("AUTH_BASIC_USER"),
 			BasicPassword: viper.GetString("AUTH_BASIC_PASSWORD"),
 		},
+		GeoIP: GeoIPConfig{
+			DBPath: viper.GetString("GEOIP_DB_PATH"),
+		},
+		Policy: PolicyConfig{
+			Blocklist:        splitList(viper.GetString("POLICY_BLOCKLIST")),
+			Allowlist:        splitList(viper.GetString("POLICY_ALLOWLIST")),
+			ThreatListFile:   viper.GetString("POLICY_THREAT_LIST_FILE"),
+			ReloadInterval:   viper.GetDuration("POLICY_RELOAD_INTERVAL"),
+			RejectIPLiterals: viper.GetBool("POLICY_REJECT_IP_LITERALS"),
+			RejectPrivate:    viper.GetBool("POLICY_REJECT_PRIVATE"),
+		},
+	}
+
+	if err := cfg.URL.validateExpiry(); err != nil {
+		return nil, err
+	}
+	if err := cfg.RateLimit.loadPlans(); err != nil {
+		return nil, err
+	}
+	if err := cfg.RateLimit.validate(); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
 
+// defaultRoute 是方案中套用於沒有個別設定之路由的限流名稱
+const defaultRoute = "default"
+
+// planEnvPrefix 回傳方案設定的環境變數前綴，例如 pro-annual 為 PLAN_PRO_ANNUAL
+func planEnvPrefix(name string) string {
+	return "PLAN_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// loadPlans 讀取 RATE_LIMIT_PLANS 列出的方案（PLAN_<NAME>_LIMITS、_DAILY_QUOTA、_MONTHLY_QUOTA、_MAX_EXPIRY）與 API_KEYS
+func (c *RateLimitConfig) loadPlans() error {
+	fallback := LimitConfig{Requests: c.Requests, Duration: c.Duration, Burst: c.Burst}
+
+	c.Plans = make(map[string]PlanConfig)
+	for _, name := range splitList(viper.GetString("RATE_LIMIT_PLANS")) {
+		prefix := planEnvPrefix(name)
+		limits, err := parseLimits(viper.GetString(prefix + "_LIMITS"))
+		if err != nil {
+			return fmt.Errorf("invalid %s_LIMITS: %w", prefix, err)
+		}
+		if _, ok := limits[defaultRoute]; !ok {
+			limits[defaultRoute] = fallback
+		}
+		c.Plans[name] = PlanConfig{
+			Name:         name,
+			Limits:       limits,
+			DailyQuota:   viper.GetInt(prefix + "_DAILY_QUOTA"),
+			MonthlyQuota: viper.GetInt(prefix + "_MONTHLY_QUOTA"),
+			MaxExpiry:    viper.GetDuration(prefix + "_MAX_EXPIRY"),
+		}
+	}
+
+	c.FailureModes = make(map[string]string)
+	for _, item := range splitList(viper.GetString("RATE_LIMIT_FAILURE_MODES")) {
+		route, mode, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(route) == "" {
+			return fmt.Errorf("invalid RATE_LIMIT_FAILURE_MODES entry %q: must be <route>=<mode>", item)
+		}
+		c.FailureModes[strings.TrimSpace(route)] = strings.TrimSpace(mode)
+	}
+
+	for _, item := range splitList(viper.GetString("API_KEYS")) {
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			// 不把 key 本身寫進錯誤訊息
+			return fmt.Errorf("invalid API_KEYS entry #%d: must be <key>:<tenant>:<plan>", len(c.APIKeys)+1)
+		}
+		c.APIKeys = append(c.APIKeys, APIKeyConfig{Key: parts[0], Tenant: parts[1], Plan: parts[2]})
+	}
+	return nil
+}
+
+// parseLimits 解析 "default=100/1m,create=10/1m:20,report=5/1h"；時間窗口為 Go duration，單位前的 1 可省略（10/m）。
+// 冒號後為 gcra 的 burst，省略時 Burst 為 0（限流時等於 requests）
+func parseLimits(s string) (map[string]LimitConfig, error) {
+	limits := make(map[string]LimitConfig)
+	for _, item := range splitList(s) {
+		route, spec, ok := strings.Cut(item, "=")
+		count, window, ok2 := strings.Cut(spec, "/")
+		if !ok || !ok2 || strings.TrimSpace(route) == "" {
+			return nil, fmt.Errorf("%q: must be <route>=<requests>/<window>[:<burst>]", item)
+		}
+		window, burstSpec, hasBurst := strings.Cut(window, ":")
+
+		requests, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || requests <= 0 {
+			return nil, fmt.Errorf("%q: requests must be a positive integer", item)
+		}
+		window = strings.TrimSpace(window)
+		if window != "" && (window[0] < '0' || window[0] > '9') {
+			window = "1" + window
+		}
+		duration, err := time.ParseDuration(window)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("%q: window must be a positive duration", item)
+		}
+
+		var burst int
+		if hasBurst {
+			burst, err = strconv.Atoi(strings.TrimSpace(burstSpec))
+			if err != nil || burst <= 0 {
+				return nil, fmt.Errorf("%q: burst must be a positive integer", item)
+			}
+		}
+
+		limits[strings.TrimSpace(route)] = LimitConfig{Requests: requests, Duration: duration, Burst: burst}
+	}
+	return limits, nil
+}
+
+// validate 檢查限流演算法（middleware.AlgorithmSlidingWindow、AlgorithmGCRA）、限流對象與方案的引用
+func (c *RateLimitConfig) validate() error {
+	switch c.Algorithm {
+	case "sliding_window", "gcra":
+	default:
+		return fmt.Errorf("invalid RATE_LIMIT_ALGORITHM %q: must be one of sliding_window, gcra", c.Algorithm)
+	}
+
+	if len(c.KeyBy) == 0 {
+		return fmt.Errorf("RATE_LIMIT_KEY_BY must not be empty")
+	}
+	for _, key := range c.KeyBy {
+		switch key {
+		case "api_key", "tenant", "ip", "route":
+		default:
+			return fmt.Errorf("invalid RATE_LIMIT_KEY_BY entry %q: must be one of api_key, tenant, ip, route", key)
+		}
+	}
+
+	modes := map[string]string{"RATE_LIMIT_FAILURE_MODE": c.FailureMode}
+	for route, mode := range c.FailureModes {
+		modes["RATE_LIMIT_FAILURE_MODES "+route] = mode
+	}
+	for name, mode := range modes {
+		switch mode {
+		case FailureModeOpen, FailureModeClosed, FailureModeLocal:
+		default:
+			return fmt.Errorf("invalid %s %q: must be one of open, closed, local", name, mode)
+		}
+	}
+	if c.Replicas < 1 {
+		return fmt.Errorf("RATE_LIMIT_REPLICAS must be at least 1")
+	}
+	if c.LocalSize < 1 {
+		return fmt.Errorf("RATE_LIMIT_LOCAL_SIZE must be at least 1")
+	}
+
+	for name, plan := range c.Plans {
+		if plan.DailyQuota < 0 || plan.MonthlyQuota < 0 {
+			return fmt.Errorf("plan %q: quotas must not be negative", name)
+		}
+		if plan.MaxExpiry < 0 {
+			return fmt.Errorf("plan %q: %s_MAX_EXPIRY must not be negative", name, planEnvPrefix(name))
+		}
+		if limit := plan.Limits[defaultRoute]; limit.Requests <= 0 || limit.Duration <= 0 {
+			return fmt.Errorf("plan %q: default limit must be positive (set %s_LIMITS or RATE_LIMIT_REQUESTS/RATE_LIMIT_DURATION)",
+				name, planEnvPrefix(name))
+		}
+	}
+	if _, ok := c.Plans[c.DefaultPlan]; !ok {
+		return fmt.Errorf("RATE_LIMIT_DEFAULT_PLAN %q is not listed in RATE_LIMIT_PLANS", c.DefaultPlan)
+	}
+
+	keys := make(map[string]bool, len(c.APIKeys))
+	tenantPlans := make(map[string]string)
+	for i, key := range c.APIKeys {
+		if _, ok := c.Plans[key.Plan]; !ok {
+			return fmt.Errorf("API_KEYS entry #%d: plan %q is not listed in RATE_LIMIT_PLANS", i+1, key.Plan)
+		}
+		if keys[key.Key] {
+			return fmt.Errorf("API_KEYS entry #%d: duplicate key", i+1)
+		}
+		keys[key.Key] = true
+		// 同一租戶的 key 共用租戶的額度，方案必須一致
+		if plan, ok := tenantPlans[key.Tenant]; ok && plan != key.Plan {
+			return fmt.Errorf("API_KEYS: tenant %q has keys on different plans (%s, %s)", key.Tenant, plan, key.Plan)
+		}
+		tenantPlans[key.Tenant] = key.Plan
+	}
+	return nil
+}
+
+// validateExpiry 檢查預設有效期不超過上限（否則沒有指定到期時間的請求一律會被拒絕）
+func (c *URLConfig) validateExpiry() error {
+	if c.DefaultExpiry < 0 || c.MaxExpiry < 0 {
+		return fmt.Errorf("URL_DEFAULT_EXPIRY and URL_MAX_EXPIRY must not be negative")
+	}
+	if c.MaxExpiry > 0 && c.DefaultExpiry > c.MaxExpiry {
+		return fmt.Errorf("URL_DEFAULT_EXPIRY (%v) exceeds URL_MAX_EXPIRY (%v)", c.DefaultExpiry, c.MaxExpiry)
+	}
+	return nil
+}
+
+// loadJobConfig 讀取 <prefix>_SCHEDULE、<prefix>_JITTER、<prefix>_TIMEOUT
+func loadJobConfig(prefix string) JobConfig {
+	return JobConfig{
+		Schedule: viper.GetString(prefix + "_SCHEDULE"),
+		Jitter:   viper.GetDuration(prefix + "_JITTER"),
+		Timeout:  viper.GetDuration(prefix + "_TIMEOUT"),
+	}
+}
+
 func setDefaults() {
 	viper.SetDefault("APP_ENV", "production")
 	viper.SetDefault("APP_BASE_URL", "http://localhost")
@@ -118,18 +548,114 @@ func setDefaults() {
 	viper.SetDefault("POSTGRES_SSLMODE", "disable")
 	viper.SetDefault("POSTGRES_MAX_CONNS", 25)
 	viper.SetDefault("POSTGRES_MIN_CONNS", 5)
+	viper.SetDefault("POSTGRES_READ_TIMEOUT", "2s")
+	viper.SetDefault("POSTGRES_WRITE_TIMEOUT", "5s")
+	viper.SetDefault("POSTGRES_READ_CONCURRENCY", 15)
+	viper.SetDefault("POSTGRES_WRITE_CONCURRENCY", 8)
+	viper.SetDefault("POSTGRES_BULKHEAD_WAIT", "100ms")
+	viper.SetDefault("POSTGRES_BREAKER_THRESHOLD", 5)
+	viper.SetDefault("POSTGRES_BREAKER_COOLDOWN", "10s")
 
 	viper.SetDefault("REDIS_HOST", "localhost")
 	viper.SetDefault("REDIS_PORT", "6379")
 	viper.SetDefault("REDIS_PASSWORD", "")
 	viper.SetDefault("REDIS_DB", 0)
 	viper.SetDefault("REDIS_POOL_SIZE", 10)
+	viper.SetDefault("REDIS_DIAL_TIMEOUT", "1s")
+	viper.SetDefault("REDIS_TIMEOUT", "500ms")
+	viper.SetDefault("REDIS_BREAKER_THRESHOLD", 5)
+	viper.SetDefault("REDIS_BREAKER_COOLDOWN", "10s")
+
+	viper.SetDefault("CACHE_LOCAL_SIZE", 10000)
+	viper.SetDefault("CACHE_LOCAL_TTL", "1m")
+	viper.SetDefault("CACHE_CODE_FILTER", true)
+	viper.SetDefault("CACHE_BLOOM_CAPACITY", 1000000)
+	viper.SetDefault("CACHE_BLOOM_FP_RATE", 0.001)
+	viper.SetDefault("CACHE_BLOOM_REFRESH", "1m")
+	viper.SetDefault("CACHE_NEGATIVE_SIZE", 100000)
+	viper.SetDefault("CACHE_NEGATIVE_TTL", "30s")
+	viper.SetDefault("CACHE_COALESCE_LOADS", true)
+	viper.SetDefault("CACHE_LOAD_LOCK", false)
+	viper.SetDefault("CACHE_LOAD_LOCK_WAIT", "200ms")
+	viper.SetDefault("CACHE_WARM_ON_START", "off")
+	viper.SetDefault("CACHE_WARM_TOP_N", 10000)
+	viper.SetDefault("CACHE_WARM_WINDOW", "168h")
+	viper.SetDefault("CACHE_WARM_RATE", 2000)
+
+	viper.SetDefault("CLICK_BUFFER_SIZE", 10000)
+	viper.SetDefault("CLICK_BUFFER_FLUSH", "30s")
+	viper.SetDefault("CLICK_SYNC_BATCH", 1000)
+
+	viper.SetDefault("LEADER_ID", "")
+	viper.SetDefault("LEADER_LEASE_TTL", "15s")
+
+	viper.SetDefault("JOB_CLICK_SYNC_SCHEDULE", "1h")
+	viper.SetDefault("JOB_CLICK_SYNC_JITTER", "0s")
+	viper.SetDefault("JOB_CLICK_SYNC_TIMEOUT", "30m")
+	viper.SetDefault("JOB_URL_PURGE_SCHEDULE", "0 3 * * *")
+	viper.SetDefault("JOB_URL_PURGE_JITTER", "10m")
+	viper.SetDefault("JOB_URL_PURGE_TIMEOUT", "30m")
 
 	viper.SetDefault("RATE_LIMIT_REQUESTS", 100)
 	viper.SetDefault("RATE_LIMIT_DURATION", "1m")
+	viper.SetDefault("RATE_LIMIT_ALGORITHM", "sliding_window")
+	viper.SetDefault("RATE_LIMIT_BURST", 0)
+	viper.SetDefault("RATE_LIMIT_KEY_BY", "api_key,ip")
+	viper.SetDefault("RATE_LIMIT_PLANS", "free,pro,enterprise")
+	viper.SetDefault("RATE_LIMIT_DEFAULT_PLAN", "free")
+	viper.SetDefault("RATE_LIMIT_QUOTA_ROUTES", "create")
+	viper.SetDefault("API_KEYS", "")
+	viper.SetDefault("RATE_LIMIT_FAILURE_MODE", "open")
+	viper.SetDefault("RATE_LIMIT_FAILURE_MODES", "create=local,report=local")
+	viper.SetDefault("RATE_LIMIT_REPLICAS", 1)
+	viper.SetDefault("RATE_LIMIT_LOCAL_SIZE", 100000)
+	// free 沒有 default：一般路由沿用 RATE_LIMIT_REQUESTS／RATE_LIMIT_DURATION
+	viper.SetDefault("PLAN_FREE_LIMITS", "create=10/1m,report=5/1h")
+	viper.SetDefault("PLAN_FREE_DAILY_QUOTA", 0)
+	viper.SetDefault("PLAN_FREE_MONTHLY_QUOTA", 0)
+	viper.SetDefault("PLAN_FREE_MAX_EXPIRY", 0)
+	viper.SetDefault("PLAN_PRO_LIMITS", "default=1000/1m,create=100/1m,report=20/1h")
+	viper.SetDefault("PLAN_PRO_DAILY_QUOTA", 10000)
+	viper.SetDefault("PLAN_PRO_MONTHLY_QUOTA", 200000)
+	viper.SetDefault("PLAN_PRO_MAX_EXPIRY", 0)
+	viper.SetDefault("PLAN_ENTERPRISE_LIMITS", "default=10000/1m,create=1000/1m,report=100/1h")
+	viper.SetDefault("PLAN_ENTERPRISE_DAILY_QUOTA", 0)
+	viper.SetDefault("PLAN_ENTERPRISE_MONTHLY_QUOTA", 0)
+	viper.SetDefault("PLAN_ENTERPRISE_MAX_EXPIRY", 0)
 
 	viper.SetDefault("URL_DEFAULT_EXPIRY", "0")
+	viper.SetDefault("URL_MAX_EXPIRY", "0")
 	viper.SetDefault("SHORT_CODE_LENGTH", 6)
+	viper.SetDefault("URL_SELF_LINK_MODE", "reject")
+	viper.SetDefault("URL_SELF_HOSTS", "")
+	viper.SetDefault("URL_KNOWN_SHORTENERS", "bit.ly,tinyurl.com,t.co,goo.gl,ow.ly,is.gd,buff.ly,rebrand.ly,cutt.ly,shorturl.at")
+	viper.SetDefault("URL_MAX_REDIRECT_DEPTH", 5)
+	viper.SetDefault("URL_DEDUP_MODE", "canonical")
+	viper.SetDefault("URL_CANON_SORT_QUERY", false)
+	viper.SetDefault("URL_CANON_STRIP_TRACKING", false)
+	viper.SetDefault("URL_PURGE_GRACE", "720h")
+	viper.SetDefault("URL_PURGE_BATCH", 1000)
+	viper.SetDefault("URL_PURGE_CODES", "tombstone")
+
+	viper.SetDefault("GEOIP_DB_PATH", "")
+
+	viper.SetDefault("POLICY_BLOCKLIST", "")
+	viper.SetDefault("POLICY_ALLOWLIST", "")
+	viper.SetDefault("POLICY_THREAT_LIST_FILE", "")
+	viper.SetDefault("POLICY_RELOAD_INTERVAL", "30s")
+	viper.SetDefault("POLICY_REJECT_IP_LITERALS", true)
+	viper.SetDefault("POLICY_REJECT_PRIVATE", true)
+}
+
+// splitList 解析逗號分隔的環境變數（viper 的 GetStringSlice 只會以空白切割）
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 func (c *PostgresConfig) DSN() string {
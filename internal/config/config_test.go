@@ -0,0 +1,152 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]LimitConfig
+		wantErr bool
+	}{
+		{"", map[string]LimitConfig{}, false},
+		{
+			"default=100/1m, create=10/1m ,report=5/1h",
+			map[string]LimitConfig{
+				"default": {Requests: 100, Duration: time.Minute},
+				"create":  {Requests: 10, Duration: time.Minute},
+				"report":  {Requests: 5, Duration: time.Hour},
+			},
+			false,
+		},
+		{"create=10/m", map[string]LimitConfig{"create": {Requests: 10, Duration: time.Minute}}, false},
+		{"create=10/90s", map[string]LimitConfig{"create": {Requests: 10, Duration: 90 * time.Second}}, false},
+		{"create=10/1m:20", map[string]LimitConfig{"create": {Requests: 10, Duration: time.Minute, Burst: 20}}, false},
+		{"create = 10 / h : 3", map[string]LimitConfig{"create": {Requests: 10, Duration: time.Hour, Burst: 3}}, false},
+		{"create", nil, true},
+		{"create=10", nil, true},
+		{"=10/1m", nil, true},
+		{"create=0/1m", nil, true},
+		{"create=x/1m", nil, true},
+		{"create=10/0s", nil, true},
+		{"create=10/-1m", nil, true},
+		{"create=10/week", nil, true},
+		{"create=10/1m:", nil, true},
+		{"create=10/1m:0", nil, true},
+		{"create=10/1m:-5", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLimits(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLimits(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseLimits(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoadPlans(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.Set("RATE_LIMIT_PLANS", "free, pro-annual")
+	viper.Set("PLAN_FREE_LIMITS", "create=10/1m")
+	viper.Set("PLAN_PRO_ANNUAL_LIMITS", "default=1000/1m,create=100/1m:200")
+	viper.Set("PLAN_PRO_ANNUAL_DAILY_QUOTA", 10000)
+	viper.Set("PLAN_PRO_ANNUAL_MAX_EXPIRY", "720h")
+	viper.Set("API_KEYS", "k1:acme:pro-annual")
+
+	c := &RateLimitConfig{Requests: 100, Duration: time.Minute, Burst: 150}
+	if err := c.loadPlans(); err != nil {
+		t.Fatalf("loadPlans: %v", err)
+	}
+
+	free := c.Plans["free"]
+	if got, want := free.Limits["default"], (LimitConfig{Requests: 100, Duration: time.Minute, Burst: 150}); got != want {
+		t.Errorf("free default = %+v, want the RATE_LIMIT_* fallback %+v", got, want)
+	}
+	pro := c.Plans["pro-annual"]
+	if got, want := pro.Limits["create"], (LimitConfig{Requests: 100, Duration: time.Minute, Burst: 200}); got != want {
+		t.Errorf("pro-annual create = %+v, want %+v", got, want)
+	}
+	if pro.DailyQuota != 10000 || pro.MaxExpiry != 30*24*time.Hour {
+		t.Errorf("pro-annual quota/expiry = %d, %v", pro.DailyQuota, pro.MaxExpiry)
+	}
+	if want := []APIKeyConfig{{Key: "k1", Tenant: "acme", Plan: "pro-annual"}}; !reflect.DeepEqual(c.APIKeys, want) {
+		t.Errorf("APIKeys = %+v, want %+v", c.APIKeys, want)
+	}
+}
+
+func TestRateLimitConfigValidate(t *testing.T) {
+	valid := func() *RateLimitConfig {
+		limit := LimitConfig{Requests: 100, Duration: time.Minute}
+		return &RateLimitConfig{
+			Algorithm:    "gcra",
+			KeyBy:        []string{"api_key", "ip"},
+			DefaultPlan:  "free",
+			FailureMode:  FailureModeOpen,
+			FailureModes: map[string]string{"create": FailureModeLocal},
+			Replicas:     1,
+			LocalSize:    100,
+			Plans: map[string]PlanConfig{
+				"free": {Name: "free", Limits: map[string]LimitConfig{defaultRoute: limit}},
+				"pro":  {Name: "pro", Limits: map[string]LimitConfig{defaultRoute: limit}},
+			},
+			APIKeys: []APIKeyConfig{{Key: "k1", Tenant: "acme", Plan: "pro"}, {Key: "k2", Tenant: "acme", Plan: "pro"}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *RateLimitConfig)
+		wantErr string // "" 表示合法
+	}{
+		{"valid", func(c *RateLimitConfig) {}, ""},
+		{"algorithm", func(c *RateLimitConfig) { c.Algorithm = "token_bucket" }, "RATE_LIMIT_ALGORITHM"},
+		{"empty key by", func(c *RateLimitConfig) { c.KeyBy = nil }, "RATE_LIMIT_KEY_BY"},
+		{"key by", func(c *RateLimitConfig) { c.KeyBy = []string{"user"} }, "RATE_LIMIT_KEY_BY"},
+		{"failure mode", func(c *RateLimitConfig) { c.FailureModes["report"] = "retry" }, "RATE_LIMIT_FAILURE_MODES report"},
+		{"replicas", func(c *RateLimitConfig) { c.Replicas = 0 }, "RATE_LIMIT_REPLICAS"},
+		{"negative quota", func(c *RateLimitConfig) {
+			plan := c.Plans["pro"]
+			plan.MonthlyQuota = -1
+			c.Plans["pro"] = plan
+		}, "quotas must not be negative"},
+		{"negative max expiry", func(c *RateLimitConfig) {
+			plan := c.Plans["pro"]
+			plan.MaxExpiry = -time.Hour
+			c.Plans["pro"] = plan
+		}, "PLAN_PRO_MAX_EXPIRY"},
+		{"missing default limit", func(c *RateLimitConfig) {
+			c.Plans["pro-annual"] = PlanConfig{Name: "pro-annual", Limits: map[string]LimitConfig{}}
+		}, "PLAN_PRO_ANNUAL_LIMITS"},
+		{"unknown default plan", func(c *RateLimitConfig) { c.DefaultPlan = "basic" }, "RATE_LIMIT_DEFAULT_PLAN"},
+		{"unknown key plan", func(c *RateLimitConfig) { c.APIKeys[1].Plan = "basic" }, "API_KEYS entry #2"},
+		{"duplicate key", func(c *RateLimitConfig) { c.APIKeys[1].Key = "k1" }, "duplicate key"},
+		{"tenant on two plans", func(c *RateLimitConfig) { c.APIKeys[1].Plan = "free" }, "different plans"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(c)
+			err := c.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate() = %v, want an error mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
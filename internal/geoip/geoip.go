@@ -0,0 +1,51 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Resolver resolves client IPs to ISO 3166-1 alpha-2 country codes using a local MaxMind database
+type Resolver struct {
+	db *geoip2.Reader
+}
+
+// Open loads the GeoIP database file (GeoLite2-Country / GeoIP2-Country .mmdb)
+func Open(path string) (*Resolver, error) {
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open geoip database: %w", err)
+	}
+
+	return &Resolver{db: db}, nil
+}
+
+func (r *Resolver) Close() error {
+	if r == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+// Country returns the upper-case country code for ip, or "" when unknown.
+// A nil Resolver is valid and always returns "" (GeoIP disabled).
+func (r *Resolver) Country(ip string) string {
+	if r == nil {
+		return ""
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	record, err := r.db.Country(parsed)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToUpper(record.Country.IsoCode)
+}
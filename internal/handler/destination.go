@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jack/golang-short-url-service/internal/model"
+)
+
+const (
+	// maxGeoTargets 限制單一短網址的 geo 規則數量，避免超大 JSON 進入快取熱路徑。
+	maxGeoTargets = 250
+	// maxVariants / maxVariantWeight 同理，限制 split 規則大小。
+	maxVariants      = 20
+	maxVariantWeight = 10000
+
+	// maxUTMParams / maxUTMValueLength 限制固定 UTM 參數的數量與長度。
+	maxUTMParams      = 10
+	maxUTMValueLength = 256
+	maxTitleLength    = 200
+
+	variantCookiePrefix = "sv_"
+	variantCookieMaxAge = 30 * 24 * 60 * 60
+)
+
+// variant 名稱會出現在 Redis key（clicks:<code>:variant:<name>）與 cookie 值中，只允許安全字元。
+var variantNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+var utmParamPattern = regexp.MustCompile(`^utm_[a-z0-9_]{1,32}$`)
+
+// resolveDestination 依請求挑選目的地：geo 規則優先，其次 variant（A/B split），最後是原始 URL。
+func (h *Handler) resolveDestination(c *gin.Context, target *model.URL) string {
+	if len(target.GeoTargets) > 0 {
+		if country := h.geo.Country(c.ClientIP()); country != "" {
+			if destination, ok := target.GeoTargets[country]; ok {
+				return destination
+			}
+		}
+	}
+
+	if len(target.Variants) > 0 {
+		if variant := pickVariant(c, target); variant != nil {
+			h.service.RecordVariantClick(target.ShortCode, variant.Name)
+			return variant.URL
+		}
+	}
+
+	return target.OriginalURL
+}
+
+// applyQueryRules 把固定 UTM 參數與（選擇性）訪客的 query string 合併進目的地。
+// 優先順序：目的地原有參數 > 固定 UTM > 訪客參數；OverrideQuery 時後兩者可取代目的地原有參數。
+// 目的地原本的 query 盡量保留原始編碼與順序，只在尾端附加新參數。
+func applyQueryRules(destination string, target *model.URL, incoming url.Values) string {
+	if len(target.UTMParams) == 0 && (!target.ForwardQuery || len(incoming) == 0) {
+		return destination
+	}
+
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+	existing, err := url.ParseQuery(parsed.RawQuery)
+	if err != nil {
+		// 目的地 query 無法解析時不冒險改寫
+		return destination
+	}
+
+	added := url.Values{}
+	for _, key := range slices.Sorted(maps.Keys(target.UTMParams)) {
+		if _, ok := existing[key]; ok && !target.OverrideQuery {
+			continue
+		}
+		added.Set(key, target.UTMParams[key])
+	}
+	if target.ForwardQuery {
+		for key, values := range incoming {
+			if _, ok := added[key]; ok {
+				continue
+			}
+			if _, ok := existing[key]; ok && !target.OverrideQuery {
+				continue
+			}
+			added[key] = values
+		}
+	}
+	if len(added) == 0 {
+		return destination
+	}
+
+	parsed.RawQuery = joinQuery(dropQueryKeys(parsed.RawQuery, added), added.Encode())
+	return parsed.String()
+}
+
+// dropQueryKeys 移除 raw query 中會被取代的參數，其餘片段原封不動保留。
+func dropQueryKeys(rawQuery string, keys url.Values) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	kept := make([]string, 0)
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(part, "=")
+		if key, err := url.QueryUnescape(name); err == nil {
+			if _, ok := keys[key]; ok {
+				continue
+			}
+		}
+		kept = append(kept, part)
+	}
+	return strings.Join(kept, "&")
+}
+
+func joinQuery(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + "&" + b
+}
+
+// pickVariant 依權重隨機挑選；sticky 模式下沿用 cookie 記住的 variant（若該 variant 仍存在）。
+func pickVariant(c *gin.Context, target *model.URL) *model.Variant {
+	cookieName := variantCookiePrefix + target.ShortCode
+
+	if target.StickyVariants {
+		if name, err := c.Cookie(cookieName); err == nil {
+			if variant := target.Variant(name); variant != nil && variant.Weight > 0 {
+				return variant
+			}
+		}
+	}
+
+	total := target.TotalVariantWeight()
+	if total <= 0 {
+		return nil
+	}
+	variant := target.PickVariant(rand.IntN(total))
+
+	if variant != nil && target.StickyVariants {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(cookieName, variant.Name, variantCookieMaxAge, "/"+target.ShortCode, "", false, true)
+	}
+
+	return variant
+}
+
+// redirectStatus 目的地會依請求變動時改用 302，避免瀏覽器快取 301 後永遠導向第一次的結果。
+func redirectStatus(u *model.URL) int {
+	if len(u.GeoTargets) > 0 || len(u.Variants) > 0 {
+		return http.StatusFound
+	}
+	return http.StatusMovedPermanently
+}
+
+func validateDestination(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return errors.New("Invalid URL")
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		return nil
+	default:
+		return errors.New("Only http/https URLs are allowed")
+	}
+}
+
+// normalizeGeoTargets 驗證 geo 規則並把國碼統一成大寫（GeoIP 回傳的 ISO code 為大寫）。
+func normalizeGeoTargets(targets map[string]string) (map[string]string, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	if len(targets) > maxGeoTargets {
+		return nil, fmt.Errorf("Too many geo_targets (max %d)", maxGeoTargets)
+	}
+
+	normalized := make(map[string]string, len(targets))
+	for country, destination := range targets {
+		cc := strings.ToUpper(strings.TrimSpace(country))
+		if len(cc) != 2 || cc[0] < 'A' || cc[0] > 'Z' || cc[1] < 'A' || cc[1] > 'Z' {
+			return nil, fmt.Errorf("Invalid country code in geo_targets: %q", country)
+		}
+		if err := validateDestination(destination); err != nil {
+			return nil, fmt.Errorf("geo_targets[%s]: %s", cc, err.Error())
+		}
+		normalized[cc] = destination
+	}
+
+	return normalized, nil
+}
+
+// normalizeVariants 驗證 split 規則：至少兩個 variant、名稱唯一、權重為正整數。
+func normalizeVariants(variants []model.Variant) ([]model.Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) < 2 || len(variants) > maxVariants {
+		return nil, fmt.Errorf("variants must contain between 2 and %d entries", maxVariants)
+	}
+
+	seen := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		if !variantNamePattern.MatchString(v.Name) {
+			return nil, fmt.Errorf("Invalid variant name: %q", v.Name)
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("Duplicate variant name: %q", v.Name)
+		}
+		seen[v.Name] = true
+
+		if v.Weight < 1 || v.Weight > maxVariantWeight {
+			return nil, fmt.Errorf("variants[%s]: weight must be between 1 and %d", v.Name, maxVariantWeight)
+		}
+		if err := validateDestination(v.URL); err != nil {
+			return nil, fmt.Errorf("variants[%s]: %s", v.Name, err.Error())
+		}
+	}
+
+	return variants, nil
+}
+
+// normalizeUTMParams 只接受 utm_* 參數（key 統一小寫），值不可為空。
+func normalizeUTMParams(params map[string]string) (map[string]string, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	if len(params) > maxUTMParams {
+		return nil, fmt.Errorf("Too many utm_params (max %d)", maxUTMParams)
+	}
+
+	normalized := make(map[string]string, len(params))
+	for key, value := range params {
+		k := strings.ToLower(strings.TrimSpace(key))
+		if !utmParamPattern.MatchString(k) {
+			return nil, fmt.Errorf("Invalid utm_params key: %q (must start with utm_)", key)
+		}
+		if value == "" || len(value) > maxUTMValueLength {
+			return nil, fmt.Errorf("utm_params[%s]: value must be 1-%d characters", k, maxUTMValueLength)
+		}
+		normalized[k] = value
+	}
+
+	return normalized, nil
+}
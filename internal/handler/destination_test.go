@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/jack/golang-short-url-service/internal/model"
+)
+
+func TestApplyQueryRules(t *testing.T) {
+	utm := map[string]string{"utm_source": "newsletter", "utm_medium": "email"}
+
+	tests := []struct {
+		name        string
+		destination string
+		target      model.URL
+		incoming    string
+		want        string
+	}{
+		{"no rules", "https://example.com/p?a=1", model.URL{}, "ref=x", "https://example.com/p?a=1"},
+		{"forwarding without a query", "https://example.com/p", model.URL{ForwardQuery: true}, "", "https://example.com/p"},
+		{"utm appended", "https://example.com/p", model.URL{UTMParams: utm}, "",
+			"https://example.com/p?utm_medium=email&utm_source=newsletter"},
+		{"destination keeps its own utm", "https://example.com/p?utm_source=partner", model.URL{UTMParams: utm}, "",
+			"https://example.com/p?utm_source=partner&utm_medium=email"},
+		{"override replaces destination utm", "https://example.com/p?utm_source=partner&a=1", model.URL{UTMParams: utm, OverrideQuery: true}, "",
+			"https://example.com/p?a=1&utm_medium=email&utm_source=newsletter"},
+		{"visitor query forwarded", "https://example.com/p?a=1", model.URL{ForwardQuery: true}, "ref=x",
+			"https://example.com/p?a=1&ref=x"},
+		{"destination wins over visitor", "https://example.com/p?a=1", model.URL{ForwardQuery: true}, "a=2&b=3",
+			"https://example.com/p?a=1&b=3"},
+		{"utm wins over visitor", "https://example.com/p", model.URL{UTMParams: utm, ForwardQuery: true}, "utm_source=spoofed&ref=x",
+			"https://example.com/p?ref=x&utm_medium=email&utm_source=newsletter"},
+		{"utm wins over visitor with override", "https://example.com/p?utm_source=partner", model.URL{UTMParams: utm, ForwardQuery: true, OverrideQuery: true}, "utm_source=spoofed",
+			"https://example.com/p?utm_medium=email&utm_source=newsletter"},
+		{"override drops every copy of a key", "https://example.com/p?a=1&b=2&a=3", model.URL{ForwardQuery: true, OverrideQuery: true}, "a=9",
+			"https://example.com/p?b=2&a=9"},
+		{"repeated visitor values", "https://example.com/p", model.URL{ForwardQuery: true}, "tag=x&tag=y",
+			"https://example.com/p?tag=x&tag=y"},
+		{"destination encoding preserved", "https://example.com/s?q=a%20b&x=%7E", model.URL{UTMParams: map[string]string{"utm_source": "n"}}, "",
+			"https://example.com/s?q=a%20b&x=%7E&utm_source=n"},
+		{"added values encoded", "https://example.com/p", model.URL{ForwardQuery: true}, "q=a+b%26c&%C3%A9=1",
+			"https://example.com/p?q=a+b%26c&%C3%A9=1"},
+		{"encoded destination key matched", "https://example.com/p?utm%5Fsource=partner", model.URL{UTMParams: map[string]string{"utm_source": "n"}, OverrideQuery: true}, "",
+			"https://example.com/p?utm_source=n"},
+		{"fragment kept", "https://example.com/p#top", model.URL{UTMParams: map[string]string{"utm_source": "n"}}, "",
+			"https://example.com/p?utm_source=n#top"},
+		{"unparseable destination query left alone", "https://example.com/p?a=%zz", model.URL{UTMParams: map[string]string{"utm_source": "n"}}, "",
+			"https://example.com/p?a=%zz"},
+		{"nothing to add", "https://example.com/p?ref=1", model.URL{ForwardQuery: true}, "ref=2",
+			"https://example.com/p?ref=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			incoming, err := url.ParseQuery(tt.incoming)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q): %v", tt.incoming, err)
+			}
+			if got := applyQueryRules(tt.destination, &tt.target, incoming); got != tt.want {
+				t.Errorf("applyQueryRules(%q) = %q, want %q", tt.destination, got, tt.want)
+			}
+		})
+	}
+}
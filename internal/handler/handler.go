@@ -1,24 +1,57 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jack/golang-short-url-service/internal/geoip"
+	"github.com/jack/golang-short-url-service/internal/leader"
+	"github.com/jack/golang-short-url-service/internal/middleware"
 	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/jack/golang-short-url-service/internal/policy"
 	"github.com/jack/golang-short-url-service/internal/repository"
+	"github.com/jack/golang-short-url-service/internal/scheduler"
 	"github.com/jack/golang-short-url-service/internal/service"
 )
 
 type Handler struct {
 	service *service.ShortURLService
+	geo     *geoip.Resolver
+	leader  *leader.Elector
+	jobs    *scheduler.Scheduler
+	limiter *middleware.RateLimiter
 }
 
-func NewHandler(service *service.ShortURLService) *Handler {
-	return &Handler{service: service}
+func NewHandler(service *service.ShortURLService, geo *geoip.Resolver, elector *leader.Elector, jobs *scheduler.Scheduler, limiter *middleware.RateLimiter) *Handler {
+	return &Handler{service: service, geo: geo, leader: elector, jobs: jobs, limiter: limiter}
+}
+
+// respondUnavailable 處理 PostgreSQL 斷路器開啟或併發已滿的暫時性錯誤（503 + Retry-After）；其他錯誤回傳 false
+func respondUnavailable(c *gin.Context, err error) bool {
+	if !errors.Is(err, repository.ErrDBUnavailable) && !errors.Is(err, repository.ErrDBBusy) {
+		return false
+	}
+
+	c.Header("Retry-After", "5")
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error":   "service_unavailable",
+		"message": "Service is temporarily unavailable, please retry later",
+	})
+	return true
+}
+
+// expiryContext 帶入呼叫者方案的最長有效期（PLAN_<NAME>_MAX_EXPIRY），由限流中介層判定的方案決定
+func (h *Handler) expiryContext(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	if plan, ok := h.limiter.Plan(c); ok {
+		ctx = service.WithMaxExpiry(ctx, plan.MaxExpiry)
+	}
+	return ctx
 }
 
 func respondInternalError(c *gin.Context, message string) {
@@ -39,26 +72,73 @@ func (h *Handler) CreateShortURL(c *gin.Context) {
 		return
 	}
 
-	parsed, err := url.Parse(req.URL)
-	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+	if err := validateDestination(req.URL); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "invalid_request",
-			"message": "Invalid URL",
+			"message": err.Error(),
 		})
 		return
 	}
-	switch strings.ToLower(parsed.Scheme) {
-	case "http", "https":
-	default:
+
+	geoTargets, err := normalizeGeoTargets(req.GeoTargets)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+	req.GeoTargets = geoTargets
+
+	variants, err := normalizeVariants(req.Variants)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "invalid_request",
-			"message": "Only http/https URLs are allowed",
+			"message": err.Error(),
 		})
 		return
 	}
+	req.Variants = variants
 
-	response, err := h.service.CreateShortURL(c.Request.Context(), &req)
+	utmParams, err := normalizeUTMParams(req.UTMParams)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+	req.UTMParams = utmParams
+
+	req.Title = strings.TrimSpace(req.Title)
+	if len(req.Title) > maxTitleLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Title is too long",
+		})
+		return
+	}
+
+	response, err := h.service.CreateShortURL(h.expiryContext(c), &req)
+	if err != nil {
+		var violation *policy.Violation
+		if errors.As(err, &violation) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   violation.Code,
+				"message": "Destination rejected by URL policy: " + violation.Host,
+			})
+			return
+		}
+		if code := createErrorCode(err); code != "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   code,
+				"message": err.Error(),
+			})
+			return
+		}
+		if respondUnavailable(c, err) {
+			return
+		}
 		log.Printf("create short url failed: ip=%s err=%v", c.ClientIP(), err)
 		respondInternalError(c, "Failed to create short URL")
 		return
@@ -67,6 +147,61 @@ func (h *Handler) CreateShortURL(c *gin.Context) {
 	c.JSON(http.StatusCreated, response)
 }
 
+// RenewURL 為短網址設定新的到期時間（過期的短網址恢復可用）；body 可省略，省略 expires_in 表示不再過期
+func (h *Handler) RenewURL(c *gin.Context) {
+	code := c.Param("code")
+
+	var req model.RenewURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.RenewURL(h.expiryContext(c), code, &req)
+	if err != nil {
+		var violation *policy.Violation
+		if errors.As(err, &violation) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   violation.Code,
+				"message": "Destination rejected by URL policy: " + violation.Host,
+			})
+			return
+		}
+		if errors.Is(err, repository.ErrURLNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "Short URL not found",
+			})
+			return
+		}
+		if errors.Is(err, repository.ErrURLDisabled) {
+			c.JSON(http.StatusGone, gin.H{
+				"error":   "disabled",
+				"message": "This short URL has been disabled and cannot be renewed",
+			})
+			return
+		}
+		if errCode := createErrorCode(err); errCode != "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   errCode,
+				"message": err.Error(),
+			})
+			return
+		}
+		if respondUnavailable(c, err) {
+			return
+		}
+		log.Printf("renew short url failed: code=%s ip=%s err=%v", code, c.ClientIP(), err)
+		respondInternalError(c, "Failed to renew short URL")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 func (h *Handler) Redirect(c *gin.Context) {
 	code := c.Param("code")
 	if code == "" {
@@ -77,7 +212,13 @@ func (h *Handler) Redirect(c *gin.Context) {
 		return
 	}
 
-	originalURL, err := h.service.GetOriginalURL(c.Request.Context(), code)
+	// Gin 無法另外註冊 /:code+，由 Redirect 轉交預覽頁
+	if strings.HasSuffix(code, previewSuffix) {
+		h.Preview(c)
+		return
+	}
+
+	target, err := h.service.GetOriginalURL(c.Request.Context(), code)
 	if err != nil {
 		if errors.Is(err, repository.ErrURLNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -93,12 +234,61 @@ func (h *Handler) Redirect(c *gin.Context) {
 			})
 			return
 		}
+		if errors.Is(err, repository.ErrURLDisabled) {
+			h.renderDisabled(c, code)
+			return
+		}
+		if respondUnavailable(c, err) {
+			return
+		}
 		log.Printf("redirect failed: code=%s ip=%s err=%v", code, c.ClientIP(), err)
 		respondInternalError(c, "Failed to retrieve URL")
 		return
 	}
 
-	c.Redirect(http.StatusMovedPermanently, originalURL)
+	if source := c.Query("utm_source"); source != "" {
+		h.service.RecordSourceClick(code, source)
+	}
+
+	destination := applyQueryRules(h.resolveDestination(c, target), target, c.Request.URL.Query())
+	destination, err = h.service.ResolveRedirectDestination(c.Request.Context(), destination)
+	if err != nil {
+		if errors.Is(err, service.ErrRedirectLoop) {
+			c.JSON(http.StatusLoopDetected, gin.H{
+				"error":   "redirect_loop",
+				"message": "This short URL redirects to itself too many times",
+			})
+			return
+		}
+		log.Printf("redirect resolve failed: code=%s ip=%s err=%v", code, c.ClientIP(), err)
+		respondInternalError(c, "Failed to retrieve URL")
+		return
+	}
+	if target.AlwaysPreview {
+		h.renderInterstitial(c, target, destination)
+		return
+	}
+	c.Redirect(redirectStatus(target), destination)
+}
+
+// createErrorCode 把建立短網址時可預期的拒絕原因轉成對外的錯誤代碼
+func createErrorCode(err error) string {
+	switch {
+	case errors.Is(err, service.ErrSelfLink):
+		return "self_link"
+	case errors.Is(err, service.ErrShortenerLink):
+		return "known_shortener"
+	case errors.Is(err, service.ErrRedirectLoop):
+		return "redirect_loop"
+	case errors.Is(err, service.ErrInvalidDestination), errors.Is(err, service.ErrInvalidExpiry):
+		return "invalid_request"
+	case errors.Is(err, service.ErrExpiryTooLong):
+		return "expiry_too_long"
+	case errors.Is(err, repository.ErrURLDisabled):
+		return "destination_disabled"
+	default:
+		return ""
+	}
 }
 
 func (h *Handler) GetStats(c *gin.Context) {
@@ -120,6 +310,9 @@ func (h *Handler) GetStats(c *gin.Context) {
 			})
 			return
 		}
+		if respondUnavailable(c, err) {
+			return
+		}
 		log.Printf("get stats failed: code=%s ip=%s err=%v", code, c.ClientIP(), err)
 		respondInternalError(c, "Failed to retrieve stats")
 		return
@@ -134,11 +327,129 @@ func (h *Handler) Health(c *gin.Context) {
 	})
 }
 
+// HealthDetailed 實際檢查 PostgreSQL 與 Redis，並回報斷路器與 bulkhead 狀態；只有 PostgreSQL 連不上時回 503
 func (h *Handler) HealthDetailed(c *gin.Context) {
+	health := h.service.Health(c.Request.Context())
+
+	status := http.StatusOK
+	if health.Status == model.HealthUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, health)
+}
+
+// CacheStats 回傳 url: 快取各層命中率（GET /api/v1/admin/cache/stats），數值為本副本自啟動起累計
+func (h *Handler) CacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.CacheStats())
+}
+
+// WarmCache 在背景預熱 Redis 快取（POST /api/v1/admin/cache/warm），立即回 202 與預熱狀態
+func (h *Handler) WarmCache(c *gin.Context) {
+	var req model.CacheWarmRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "Invalid request body: " + err.Error(),
+			})
+			return
+		}
+	}
+	if req.Mode == "" {
+		req.Mode = model.CacheWarmTop
+	}
+	if req.Limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "limit must not be negative",
+		})
+		return
+	}
+
+	status, err := h.service.StartCacheWarm(req.Mode, req.Limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidWarmMode) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrWarmRunning) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "warm_running",
+				"message": "A cache warm is already running",
+				"status":  status,
+			})
+			return
+		}
+		log.Printf("cache warm failed to start: ip=%s err=%v", c.ClientIP(), err)
+		respondInternalError(c, "Failed to start cache warm")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, status)
+}
+
+// WarmCacheStatus 回傳本副本目前或上一次預熱的狀態（GET /api/v1/admin/cache/warm）
+func (h *Handler) WarmCacheStatus(c *gin.Context) {
+	status := h.service.CacheWarmStatus()
+	if status == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "No cache warm has run on this instance",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// LeaderStatus 回傳點擊同步目前的 leader 與本副本的狀態（GET /api/v1/admin/leader）
+func (h *Handler) LeaderStatus(c *gin.Context) {
+	status, err := h.leader.Status(c.Request.Context())
+	if err != nil {
+		log.Printf("leader status failed: err=%v", err)
+		respondInternalError(c, "Failed to read leader lease")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// ListJobs 回傳本副本所有背景 job 的排程與上次執行狀態（GET /api/v1/admin/jobs）
+func (h *Handler) ListJobs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"status":   "healthy",
-		"postgres": "connected",
-		"redis":    "connected",
+		"instance": h.leader.ID(),
+		"jobs":     h.jobs.Jobs(),
 	})
 }
 
+// RunJob 立即在背景執行一次 job（POST /api/v1/admin/jobs/:name/run），回 202 與 job 狀態
+func (h *Handler) RunJob(c *gin.Context) {
+	status, err := h.jobs.Trigger(c.Param("name"))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusAccepted, status)
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Job not found",
+		})
+	case errors.Is(err, scheduler.ErrJobRunning):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "job_running",
+			"message": "Job is already running",
+			"status":  status,
+		})
+	case errors.Is(err, scheduler.ErrNotLeader):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "not_leader",
+			"message":  "Job runs on the leader only; retry until the request reaches the leader (see /api/v1/admin/leader)",
+			"instance": h.leader.ID(),
+		})
+	default:
+		log.Printf("run job failed: name=%s err=%v", c.Param("name"), err)
+		respondInternalError(c, "Failed to run job")
+	}
+}
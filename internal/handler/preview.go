@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/jack/golang-short-url-service/internal/repository"
+)
+
+// previewSuffix：在短碼後加 "+"（/abc123+）即可預覽目的地而不跳轉。
+const previewSuffix = "+"
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var pageTemplates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+type previewPage struct {
+	Title        string
+	ShortURL     string
+	Destination  string
+	CreatedAt    string
+	ClickCount   int64
+	ContinueURL  string
+	Interstitial bool
+}
+
+type disabledPage struct {
+	ShortURL string
+}
+
+// Preview 顯示短網址的目的地與統計，不計入點擊數（GET /preview/:code 或 GET /:code+）。
+func (h *Handler) Preview(c *gin.Context) {
+	code := strings.TrimSuffix(c.Param("code"), previewSuffix)
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Short code is required",
+		})
+		return
+	}
+
+	stats, err := h.service.GetURLStats(c.Request.Context(), code)
+	if err != nil {
+		if errors.Is(err, repository.ErrURLNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "Short URL not found",
+			})
+			return
+		}
+		if respondUnavailable(c, err) {
+			return
+		}
+		log.Printf("preview failed: code=%s ip=%s err=%v", code, c.ClientIP(), err)
+		respondInternalError(c, "Failed to retrieve URL")
+		return
+	}
+
+	// 停用（檢舉下架、安全政策）的短網址不顯示目的地
+	if !stats.IsActive {
+		h.renderDisabled(c, stats.ShortCode)
+		return
+	}
+
+	page := previewPage{
+		Title:       stats.Title,
+		ShortURL:    h.service.ShortURL(stats.ShortCode),
+		Destination: stats.OriginalURL,
+		CreatedAt:   stats.CreatedAt.Format(time.RFC1123),
+		ClickCount:  stats.ClickCount,
+	}
+
+	status := http.StatusOK
+	if !statsExpired(stats) {
+		page.ContinueURL = stats.OriginalURL
+	} else {
+		status = http.StatusGone
+	}
+
+	renderPage(c, status, "preview.html", page)
+}
+
+// renderInterstitial 用於 always_preview 的短網址：點擊已在 GetOriginalURL 計入，
+// 頁面上的「繼續」連結直接指向依本次請求挑選好的目的地。
+func (h *Handler) renderInterstitial(c *gin.Context, target *model.URL, destination string) {
+	page := previewPage{
+		Title:        target.Title,
+		ShortURL:     h.service.ShortURL(target.ShortCode),
+		Destination:  destination,
+		CreatedAt:    target.CreatedAt.Format(time.RFC1123),
+		ClickCount:   target.ClickCount,
+		ContinueURL:  destination,
+		Interstitial: true,
+	}
+
+	// 快取中的 model.URL 點擊數可能過時，盡量以 stats（DB + 尚未同步）為準
+	if stats, err := h.service.GetURLStats(c.Request.Context(), target.ShortCode); err == nil {
+		page.ClickCount = stats.ClickCount
+	} else {
+		log.Printf("interstitial stats failed: code=%s err=%v", target.ShortCode, err)
+	}
+
+	renderPage(c, http.StatusOK, "preview.html", page)
+}
+
+// renderDisabled 顯示統一的「此連結已停用」頁面（410）
+func (h *Handler) renderDisabled(c *gin.Context, code string) {
+	renderPage(c, http.StatusGone, "disabled.html", disabledPage{ShortURL: h.service.ShortURL(code)})
+}
+
+func renderPage(c *gin.Context, status int, name string, data any) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex, nofollow")
+	c.Render(status, render.HTML{Template: pageTemplates, Name: name, Data: data})
+}
+
+func statsExpired(stats *model.URLStatsResponse) bool {
+	if stats.ExpiresAt == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, stats.ExpiresAt)
+	return err == nil && time.Now().After(expiresAt)
+}
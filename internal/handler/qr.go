@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jack/golang-short-url-service/internal/qr"
+	"github.com/jack/golang-short-url-service/internal/repository"
+	"github.com/jack/golang-short-url-service/internal/service"
+)
+
+const (
+	defaultQRSize   = 256
+	minQRSize       = 64
+	maxQRSize       = 2048
+	defaultQRMargin = 4
+	maxQRMargin     = 16
+
+	// qrRenderVersion 變更渲染方式時遞增，讓既有的 ETag 失效
+	qrRenderVersion = "1"
+)
+
+// QRCode 產生短網址的 QR code（GET /api/v1/urls/:code/qr?format=png|svg&size=&margin=&ecc=&utm=）。
+// 圖片內容只由參數決定；確認短網址存在且可使用後才比對 ETag，不存在回 404，停用或過期回 410。
+func (h *Handler) QRCode(c *gin.Context) {
+	code := c.Param("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Short code is required",
+		})
+		return
+	}
+
+	opts, err := parseQROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	content := h.service.ShortURL(code)
+	// utm=true 時在 QR 內容加上 utm_source=qr，統計時可區分掃碼與其他流量
+	if utm, _ := strconv.ParseBool(c.Query("utm")); utm {
+		content += "?" + url.Values{"utm_source": {service.SourceQR}}.Encode()
+	}
+
+	// 先確認短網址存在且仍可使用，停用或過期後不再回 304 讓用戶端沿用舊圖
+	if err := h.service.CheckAvailable(c.Request.Context(), code); err != nil {
+		if errors.Is(err, repository.ErrURLNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "Short URL not found",
+			})
+			return
+		}
+		if errors.Is(err, repository.ErrURLExpired) {
+			c.JSON(http.StatusGone, gin.H{
+				"error":   "expired",
+				"message": "This short URL has expired",
+			})
+			return
+		}
+		if errors.Is(err, repository.ErrURLDisabled) {
+			c.JSON(http.StatusGone, gin.H{
+				"error":   "disabled",
+				"message": "This short URL has been disabled",
+			})
+			return
+		}
+		if respondUnavailable(c, err) {
+			return
+		}
+		log.Printf("qr code lookup failed: code=%s ip=%s err=%v", code, c.ClientIP(), err)
+		respondInternalError(c, "Failed to generate QR code")
+		return
+	}
+
+	etag := qrETag(content, opts)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=86400")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	data, contentType, err := qr.Render(content, opts)
+	if err != nil {
+		c.Header("ETag", "")
+		c.Header("Cache-Control", "no-store")
+		log.Printf("qr code render failed: code=%s err=%v", code, err)
+		respondInternalError(c, "Failed to generate QR code")
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func parseQROptions(c *gin.Context) (qr.Options, error) {
+	opts := qr.Options{
+		Format: c.DefaultQuery("format", qr.FormatPNG),
+		Size:   defaultQRSize,
+		Margin: defaultQRMargin,
+		ECC:    c.DefaultQuery("ecc", "M"),
+	}
+
+	switch opts.Format {
+	case qr.FormatPNG, qr.FormatSVG:
+	default:
+		return opts, errors.New("format must be png or svg")
+	}
+
+	switch opts.ECC {
+	case "L", "M", "Q", "H":
+	default:
+		return opts, errors.New("ecc must be one of L, M, Q, H")
+	}
+
+	if raw := c.Query("size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < minQRSize || size > maxQRSize {
+			return opts, fmt.Errorf("size must be between %d and %d", minQRSize, maxQRSize)
+		}
+		opts.Size = size
+	}
+
+	if raw := c.Query("margin"); raw != "" {
+		margin, err := strconv.Atoi(raw)
+		if err != nil || margin < 0 || margin > maxQRMargin {
+			return opts, fmt.Errorf("margin must be between 0 and %d", maxQRMargin)
+		}
+		opts.Margin = margin
+	}
+
+	return opts, nil
+}
+
+func qrETag(content string, opts qr.Options) string {
+	key := fmt.Sprintf("%s|%s|%s|%d|%d|%s", qrRenderVersion, content, opts.Format, opts.Size, opts.Margin, opts.ECC)
+	sum := sha256.Sum256([]byte(key))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/jack/golang-short-url-service/internal/repository"
+)
+
+const (
+	maxReportDetailsLength = 2000
+	maxReportNoteLength    = 2000
+	maxReportUserAgent     = 512
+	defaultReportPageSize  = 50
+	maxReportPageSize      = 200
+)
+
+var reportReasons = map[string]bool{
+	model.ReportReasonPhishing: true,
+	model.ReportReasonMalware:  true,
+	model.ReportReasonSpam:     true,
+	model.ReportReasonIllegal:  true,
+	model.ReportReasonOther:    true,
+}
+
+// ReportAbuse 公開的檢舉入口（POST /api/v1/report/:code）。不需驗證碼，靠專用限流擋濫用；
+// 為避免被拿來探測審核狀態，成功、重複檢舉或短網址已停用都回同樣的 202。
+func (h *Handler) ReportAbuse(c *gin.Context) {
+	code := c.Param("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Short code is required",
+		})
+		return
+	}
+
+	var req model.CreateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	req.Reason = strings.ToLower(strings.TrimSpace(req.Reason))
+	if !reportReasons[req.Reason] {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "reason must be one of phishing, malware, spam, illegal, other",
+		})
+		return
+	}
+	req.Details = strings.TrimSpace(req.Details)
+	if len(req.Details) > maxReportDetailsLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Details is too long",
+		})
+		return
+	}
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxReportUserAgent {
+		userAgent = userAgent[:maxReportUserAgent]
+	}
+
+	if err := h.service.ReportAbuse(c.Request.Context(), code, &req, c.ClientIP(), userAgent); err != nil {
+		if errors.Is(err, repository.ErrURLNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "Short URL not found",
+			})
+			return
+		}
+		log.Printf("report abuse failed: code=%s ip=%s err=%v", code, c.ClientIP(), err)
+		respondInternalError(c, "Failed to submit report")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, model.CreateReportResponse{
+		Status:  "received",
+		Message: "Thank you. The report will be reviewed.",
+	})
+}
+
+// ListReports 列出檢舉（GET /api/v1/admin/reports?status=open&after=&limit=），status=all 列出全部。
+func (h *Handler) ListReports(c *gin.Context) {
+	status := c.DefaultQuery("status", model.ReportStatusOpen)
+	switch status {
+	case model.ReportStatusOpen, model.ReportStatusActioned, model.ReportStatusDismissed:
+	case "all":
+		status = ""
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "status must be one of open, actioned, dismissed, all",
+		})
+		return
+	}
+
+	afterID, err := strconv.ParseInt(c.DefaultQuery("after", "0"), 10, 64)
+	if err != nil || afterID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "after must be a non-negative integer",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultReportPageSize)))
+	if err != nil || limit < 1 || limit > maxReportPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "limit must be between 1 and " + strconv.Itoa(maxReportPageSize),
+		})
+		return
+	}
+
+	response, err := h.service.ListAbuseReports(c.Request.Context(), status, afterID, limit)
+	if err != nil {
+		log.Printf("list reports failed: err=%v", err)
+		respondInternalError(c, "Failed to list reports")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetReport 取得單筆檢舉（GET /api/v1/admin/reports/:id）
+func (h *Handler) GetReport(c *gin.Context) {
+	id, ok := reportID(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetAbuseReport(c.Request.Context(), id)
+	if err != nil {
+		respondReportError(c, id, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// DisableReport 採納檢舉並停用短網址（POST /api/v1/admin/reports/:id/disable）
+func (h *Handler) DisableReport(c *gin.Context) {
+	h.resolveReport(c, true)
+}
+
+// DismissReport 駁回檢舉（POST /api/v1/admin/reports/:id/dismiss）
+func (h *Handler) DismissReport(c *gin.Context) {
+	h.resolveReport(c, false)
+}
+
+func (h *Handler) resolveReport(c *gin.Context, disable bool) {
+	id, ok := reportID(c)
+	if !ok {
+		return
+	}
+
+	var req model.ResolveReportRequest
+	// body 可省略
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "Invalid request body: " + err.Error(),
+			})
+			return
+		}
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if len(req.Note) > maxReportNoteLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Note is too long",
+		})
+		return
+	}
+
+	admin := c.GetString(gin.AuthUserKey)
+
+	var report *model.AbuseReport
+	var err error
+	if disable {
+		report, err = h.service.DisableReportedURL(c.Request.Context(), id, admin, req.Note)
+	} else {
+		report, err = h.service.DismissAbuseReport(c.Request.Context(), id, admin, req.Note)
+	}
+	if err != nil {
+		respondReportError(c, id, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func reportID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Report id must be a positive integer",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func respondReportError(c *gin.Context, id int64, err error) {
+	switch {
+	case errors.Is(err, repository.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Report not found",
+		})
+	case errors.Is(err, repository.ErrReportResolved):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "already_resolved",
+			"message": "Report has already been resolved",
+		})
+	default:
+		log.Printf("abuse report failed: id=%d err=%v", id, err)
+		respondInternalError(c, "Failed to process report")
+	}
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jack/golang-short-url-service/internal/middleware"
+	"github.com/jack/golang-short-url-service/internal/repository"
+)
+
+// Usage 回傳呼叫者（X-API-Key，沒有時為 IP）的方案、各路由限流與本日／本月配額用量（GET /api/v1/usage）
+func (h *Handler) Usage(c *gin.Context) {
+	usage, err := h.limiter.Usage(c)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, usage)
+	case errors.Is(err, middleware.ErrInvalidAPIKey):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_api_key",
+			"message": "Unknown API key",
+		})
+	case errors.Is(err, repository.ErrRedisUnavailable):
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "service_unavailable",
+			"message": "Usage counters are temporarily unavailable, please retry later",
+		})
+	default:
+		log.Printf("usage lookup failed: err=%v", err)
+		respondInternalError(c, "Failed to read usage")
+	}
+}
+
+// RateLimitStatus 回傳本副本各路由的限流狀態：Redis 出錯時的處理、目前是否已切換與切換次數（GET /api/v1/admin/ratelimit）
+func (h *Handler) RateLimitStatus(c *gin.Context) {
+	status := h.limiter.Status()
+	status.Instance = h.leader.ID()
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,263 @@
+// Package leader elects one replica to run a singleton job, using a Redis lease with fencing tokens.
+//
+// 取得 lease 時 Redis 會發給一個單調遞增的 fencing token；leader 每 LeaseTTL/3 續約一次，
+// 續約失敗超過 LeaseTTL 就視為失去 leadership。停止時主動交出 lease，其他副本不必等到過期。
+// 暫停（GC、網路分割）後才醒來的舊 leader 仍可能以為自己是 leader，因此寫入端要檢查 fencing token。
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/config"
+	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/jack/golang-short-url-service/internal/repository"
+)
+
+var errNotLeading = errors.New("not the leader")
+
+type Elector struct {
+	repo *repository.RedisRepository
+	name string
+	id   string
+	ttl  time.Duration
+
+	mu         sync.Mutex
+	fence      int64
+	leaseUntil time.Time
+	since      time.Time
+	onElected  []func()
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New 建立名為 name 的選舉；cfg.ID 為空時以 hostname 加上隨機字尾識別本副本
+func New(repo *repository.RedisRepository, name string, cfg *config.LeaderConfig) *Elector {
+	ttl := cfg.LeaseTTL
+	if ttl <= 0 {
+		ttl = 15 * time.Second
+	}
+
+	id := cfg.ID
+	if id == "" {
+		id = defaultID()
+	}
+
+	return &Elector{
+		repo:   repo,
+		name:   name,
+		id:     id,
+		ttl:    ttl,
+		stopCh: make(chan struct{}),
+	}
+}
+
+func defaultID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "instance"
+	}
+	buf := make([]byte, 3)
+	_, _ = rand.Read(buf)
+	return host + "-" + hex.EncodeToString(buf)
+}
+
+// ID 回傳本副本在 lease 中的識別
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// OnElected 註冊成為 leader 時要執行的 callback（在選舉 goroutine 中執行，應盡快返回）
+func (e *Elector) OnElected(fn func()) {
+	e.mu.Lock()
+	e.onElected = append(e.onElected, fn)
+	e.mu.Unlock()
+}
+
+// Start 先同步嘗試一次取得 lease，之後在背景定期續約或重試
+func (e *Elector) Start() {
+	e.tick()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.tick()
+			case <-e.stopCh:
+				return
+			}
+		}
+	}()
+	log.Printf("Leader election started: name=%s id=%s lease=%v", e.name, e.id, e.ttl)
+}
+
+// Stop 停止續約，並在仍是 leader 時交出 lease
+func (e *Elector) Stop() {
+	close(e.stopCh)
+	e.wg.Wait()
+
+	fence, leading := e.Leading()
+	if !leading {
+		return
+	}
+	e.stepDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := e.repo.ReleaseLease(ctx, e.name, e.id, fence); err != nil {
+		log.Printf("leader lease release failed: name=%s err=%v", e.name, err)
+		return
+	}
+	log.Printf("Leadership released: name=%s id=%s", e.name, e.id)
+}
+
+// Leading 回傳本副本是否為 leader 與其 fencing token；以本地記錄的到期時間判斷，Redis 斷線時也會如期失效
+func (e *Elector) Leading() (int64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.fence == 0 || !time.Now().Before(e.leaseUntil) {
+		return 0, false
+	}
+	return e.fence, true
+}
+
+// Status 回傳目前的 leader（讀取 Redis 上的 lease）與本副本的狀態
+func (e *Elector) Status(ctx context.Context) (model.LeaderStatus, error) {
+	status := model.LeaderStatus{Name: e.name, Instance: e.id}
+
+	lease, err := e.repo.GetLease(ctx, e.name)
+	if err != nil {
+		return status, err
+	}
+	if lease != nil {
+		status.Leader = lease.Holder
+		status.Fence = lease.Fence
+		if lease.TTL > 0 {
+			expiresAt := time.Now().Add(lease.TTL)
+			status.LeaseExpiresAt = &expiresAt
+		}
+	}
+
+	fence, leading := e.Leading()
+	status.IsLeader = leading && lease != nil && lease.Holder == e.id && lease.Fence == fence
+	if status.IsLeader {
+		e.mu.Lock()
+		since := e.since
+		e.mu.Unlock()
+		status.LeaderSince = &since
+	}
+	return status, nil
+}
+
+// Refence 在寫入端以 fencing token 過舊拒絕、但 Redis 上的 lease 仍是自己的時呼叫：
+// 這只會發生在 Redis 資料遺失（meta:fence: 計數器歸零）之後，換發一個大於 floor 的 token 讓 leader 可以繼續寫入。
+func (e *Elector) Refence(ctx context.Context, floor int64) (int64, error) {
+	fence, leading := e.Leading()
+	if !leading {
+		return 0, errNotLeading
+	}
+
+	newFence, ok, err := e.repo.RefenceLease(ctx, e.name, e.id, fence, floor, e.ttl)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		e.stepDown()
+		log.Printf("Leadership lost: name=%s id=%s (lease taken over)", e.name, e.id)
+		return 0, errNotLeading
+	}
+
+	e.mu.Lock()
+	e.fence = newFence
+	e.mu.Unlock()
+	log.Printf("Leader fencing token reissued: name=%s id=%s fence=%d (was %d, floor %d)", e.name, e.id, newFence, fence, floor)
+	return newFence, nil
+}
+
+func (e *Elector) tick() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.ttl/3)
+	defer cancel()
+
+	if fence, leading := e.Leading(); leading {
+		e.renew(ctx, fence)
+		return
+	}
+
+	e.mu.Lock()
+	wasLeader := e.fence != 0
+	e.mu.Unlock()
+	if wasLeader {
+		// 續約一直失敗，lease 已在本地到期
+		e.stepDown()
+		log.Printf("Leadership lost: name=%s id=%s (lease expired)", e.name, e.id)
+	}
+
+	e.acquire(ctx)
+}
+
+func (e *Elector) renew(ctx context.Context, fence int64) {
+	start := time.Now()
+	renewed, err := e.repo.RenewLease(ctx, e.name, e.id, fence, e.ttl)
+	if err != nil {
+		// 暫時性錯誤：保留目前的到期時間，到期前還有機會續約
+		if !errors.Is(err, repository.ErrRedisUnavailable) {
+			log.Printf("leader lease renew failed: name=%s err=%v", e.name, err)
+		}
+		return
+	}
+	if !renewed {
+		e.stepDown()
+		log.Printf("Leadership lost: name=%s id=%s (lease taken over)", e.name, e.id)
+		return
+	}
+
+	e.mu.Lock()
+	e.leaseUntil = start.Add(e.ttl)
+	e.mu.Unlock()
+}
+
+func (e *Elector) acquire(ctx context.Context) {
+	start := time.Now()
+	fence, acquired, err := e.repo.AcquireLease(ctx, e.name, e.id, e.ttl)
+	if err != nil {
+		if !errors.Is(err, repository.ErrRedisUnavailable) {
+			log.Printf("leader lease acquire failed: name=%s err=%v", e.name, err)
+		}
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	e.mu.Lock()
+	e.fence = fence
+	e.leaseUntil = start.Add(e.ttl)
+	e.since = time.Now()
+	fns := append([]func(){}, e.onElected...)
+	e.mu.Unlock()
+
+	log.Printf("Elected leader: name=%s id=%s fence=%d", e.name, e.id, fence)
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+func (e *Elector) stepDown() {
+	e.mu.Lock()
+	e.fence = 0
+	e.leaseUntil = time.Time{}
+	e.mu.Unlock()
+}
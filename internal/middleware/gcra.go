@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript 實作 GCRA（Generic Cell Rate Algorithm，等同 token bucket）：
+// key 只存「理論到達時間」TAT（微秒），每個請求把 TAT 往後推一個發放間隔；
+// TAT 超前現在超過 burst 個間隔時拒絕。檢查與更新在同一個腳本內完成，併發請求不會同時通過。
+// 時間取自 Redis 的 TIME，各副本的時鐘誤差不影響結果。
+//
+// KEYS[1] = key；ARGV[1] = 發放間隔（微秒）；ARGV[2] = burst
+// 回傳 {allowed, remaining, retry_after（微秒）, reset（微秒，距離額度完全恢復）}
+var gcraScript = redis.NewScript(`
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000000 + tonumber(clock[2])
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1])) or now
+if tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - burst * interval
+if now < allow_at then
+	return {0, 0, allow_at - now, tat - now}
+end
+
+redis.call('SET', KEYS[1], string.format('%.0f', new_tat), 'PX', math.ceil((new_tat - now) / 1000))
+return {1, math.floor((now - allow_at) / interval), 0, new_tat - now}
+`)
+
+// allowGCRA 以一次 EVALSHA 判斷並記錄請求；burst 個請求可以同時通過，之後每 duration/requests 恢復一個
+func (rl *RateLimiter) allowGCRA(ctx context.Context, key string, limit routeLimit) (rateDecision, error) {
+	result, err := gcraScript.Run(ctx, rl.client, []string{key}, gcraInterval(limit), limit.burst).Int64Slice()
+	if err != nil {
+		return rateDecision{}, err
+	}
+	return gcraDecision(result, limit, time.Now()), nil
+}
+
+// gcraInterval 是每恢復一個請求的間隔（微秒，至少 1）
+func gcraInterval(limit routeLimit) int64 {
+	return max(limit.duration.Microseconds()/int64(max(limit.requests, 1)), 1)
+}
+
+// gcraDecision 把 gcraScript 的回傳值換成 rateDecision；時間以本機的 now 為基準
+func gcraDecision(result []int64, limit routeLimit, now time.Time) rateDecision {
+	return rateDecision{
+		allowed:    result[0] == 1,
+		limit:      limit.burst,
+		remaining:  int(result[1]),
+		retryAfter: time.Duration(result[2]) * time.Microsecond,
+		reset:      now.Add(time.Duration(result[3]) * time.Microsecond),
+	}
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/config"
+)
+
+func TestGCRAInterval(t *testing.T) {
+	tests := []struct {
+		limit routeLimit
+		want  int64
+	}{
+		{routeLimit{requests: 10, duration: time.Minute}, 6000000},
+		{routeLimit{requests: 3, duration: time.Second}, 333333},
+		{routeLimit{requests: 0, duration: time.Second}, 1000000},
+		{routeLimit{requests: 1000, duration: time.Microsecond}, 1},
+	}
+
+	for _, tt := range tests {
+		if got := gcraInterval(tt.limit); got != tt.want {
+			t.Errorf("gcraInterval(%+v) = %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestGCRADecision(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	limit := routeLimit{name: "create", requests: 10, duration: time.Minute, burst: 20}
+
+	tests := []struct {
+		name   string
+		result []int64
+		want   rateDecision
+	}{
+		{
+			"allowed",
+			[]int64{1, 19, 0, 6000000},
+			rateDecision{allowed: true, limit: 20, remaining: 19, reset: now.Add(6 * time.Second)},
+		},
+		{
+			"allowed with the burst used up",
+			[]int64{1, 0, 0, 120000000},
+			rateDecision{allowed: true, limit: 20, remaining: 0, reset: now.Add(2 * time.Minute)},
+		},
+		{
+			"rejected",
+			[]int64{0, 0, 1500000, 119000000},
+			rateDecision{limit: 20, retryAfter: 1500 * time.Millisecond, reset: now.Add(119 * time.Second)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gcraDecision(tt.result, limit, now)
+			if got != tt.want {
+				t.Errorf("gcraDecision(%v) = %+v, want %+v", tt.result, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteLimitBurst(t *testing.T) {
+	plan := config.PlanConfig{
+		Limits: map[string]config.LimitConfig{
+			"create": {Requests: 10, Duration: time.Minute},
+			"report": {Requests: 5, Duration: time.Hour, Burst: 2},
+		},
+	}
+	rl := &RateLimiter{}
+
+	if got := rl.routeLimit(plan, "create"); got.burst != 10 {
+		t.Errorf("routeLimit(create).burst = %d, want 10 (requests)", got.burst)
+	}
+	if got := rl.routeLimit(plan, "report"); got.burst != 2 {
+		t.Errorf("routeLimit(report).burst = %d, want 2", got.burst)
+	}
+}
+
+func TestCeilSeconds(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int64
+	}{
+		{-time.Second, 1},
+		{0, 1},
+		{time.Nanosecond, 1},
+		{time.Second, 1},
+		{time.Second + time.Nanosecond, 2},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+	}
+
+	for _, tt := range tests {
+		if got := ceilSeconds(tt.d); got != tt.want {
+			t.Errorf("ceilSeconds(%v) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestCeilUnix(t *testing.T) {
+	base := time.Unix(1772359200, 0)
+	tests := []struct {
+		t    time.Time
+		want int64
+	}{
+		{base, 1772359200},
+		{base.Add(time.Nanosecond), 1772359201},
+		{base.Add(999 * time.Millisecond), 1772359201},
+		{base.Add(-time.Nanosecond), 1772359200},
+	}
+
+	for _, tt := range tests {
+		if got := ceilUnix(tt.t); got != tt.want {
+			t.Errorf("ceilUnix(%v) = %d, want %d", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestFormatInt64(t *testing.T) {
+	for _, n := range []int64{0, 7, -7, 1234567890, math.MinInt64 + 1} {
+		if got, want := formatInt64(n), strconv.FormatInt(n, 10); got != want {
+			t.Errorf("formatInt64(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
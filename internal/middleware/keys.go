@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jack/golang-short-url-service/internal/config"
+)
+
+// APIKeyHeader 是用戶端帶 API key 的 header
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyContextKey 是驗證過的 API key（*apiKey）在 gin.Context 中的 key
+const apiKeyContextKey = "ratelimit.api_key"
+
+// subjectContextKey 是 Route 判定的限流對象（Subject）在 gin.Context 中的 key
+const subjectContextKey = "ratelimit.subject"
+
+// ErrInvalidAPIKey 表示請求帶了 API_KEYS 沒有設定的 key
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// Subject 是一個限流對象：Key 區分計數（同一個 Key 共用額度），Plan 決定額度
+type Subject struct {
+	Key    string
+	Tenant string
+	Plan   string
+}
+
+// KeyExtractor 從請求取出限流對象；ok 為 false 時改用下一個 extractor（RATE_LIMIT_KEY_BY 的順序）
+type KeyExtractor func(c *gin.Context) (Subject, bool)
+
+// apiKey 是 API_KEYS 中的一把 key；id 是 key 的雜湊前綴，用於 Redis key 與回應，不暴露 key 本身
+type apiKey struct {
+	id     string
+	tenant string
+	plan   string
+}
+
+// newKeyExtractors 依 RATE_LIMIT_KEY_BY 建立 extractor 鏈；沒有帶 API key 的請求使用 defaultPlan
+func newKeyExtractors(names []string, defaultPlan string) []KeyExtractor {
+	extractors := make([]KeyExtractor, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case "api_key":
+			extractors = append(extractors, func(c *gin.Context) (Subject, bool) {
+				key, ok := requestAPIKey(c)
+				if !ok {
+					return Subject{}, false
+				}
+				return Subject{Key: "key:" + key.id, Tenant: key.tenant, Plan: key.plan}, true
+			})
+		case "tenant":
+			extractors = append(extractors, func(c *gin.Context) (Subject, bool) {
+				key, ok := requestAPIKey(c)
+				if !ok {
+					return Subject{}, false
+				}
+				return Subject{Key: "tenant:" + key.tenant, Tenant: key.tenant, Plan: key.plan}, true
+			})
+		case "ip":
+			extractors = append(extractors, func(c *gin.Context) (Subject, bool) {
+				return Subject{Key: "ip:" + c.ClientIP(), Plan: defaultPlan}, true
+			})
+		case "route":
+			// 所有請求共用同一份額度：每條路由的總量上限
+			extractors = append(extractors, func(c *gin.Context) (Subject, bool) {
+				return Subject{Key: "all", Plan: defaultPlan}, true
+			})
+		}
+	}
+	return extractors
+}
+
+// newAPIKeys 以 key 的 SHA-256 建立查詢表（查詢時間與 key 內容無關）
+func newAPIKeys(keys []config.APIKeyConfig) map[[sha256.Size]byte]apiKey {
+	lookup := make(map[[sha256.Size]byte]apiKey, len(keys))
+	for _, key := range keys {
+		sum := sha256.Sum256([]byte(key.Key))
+		lookup[sum] = apiKey{id: hex.EncodeToString(sum[:8]), tenant: key.Tenant, plan: key.Plan}
+	}
+	return lookup
+}
+
+// subject 驗證 X-API-Key 後依序嘗試 extractor；帶了未知的 key 時回傳 ErrInvalidAPIKey，不降級為匿名
+func (rl *RateLimiter) subject(c *gin.Context) (Subject, error) {
+	if raw := c.GetHeader(APIKeyHeader); raw != "" {
+		key, ok := rl.apiKeys[sha256.Sum256([]byte(raw))]
+		if !ok {
+			return Subject{}, ErrInvalidAPIKey
+		}
+		c.Set(apiKeyContextKey, &key)
+	}
+
+	for _, extract := range rl.extractors {
+		if subject, ok := extract(c); ok {
+			return subject, nil
+		}
+	}
+	// RATE_LIMIT_KEY_BY 只有 api_key／tenant 且請求沒有帶 key
+	return Subject{Key: "ip:" + c.ClientIP(), Plan: rl.defaultPlan}, nil
+}
+
+// Plan 回傳 Route 為這個請求判定的方案；請求沒有經過 Route 時 ok 為 false
+func (rl *RateLimiter) Plan(c *gin.Context) (config.PlanConfig, bool) {
+	value, ok := c.Get(subjectContextKey)
+	if !ok {
+		return config.PlanConfig{}, false
+	}
+	subject, ok := value.(Subject)
+	if !ok {
+		return config.PlanConfig{}, false
+	}
+	plan, ok := rl.plans[subject.Plan]
+	return plan, ok
+}
+
+func requestAPIKey(c *gin.Context) (*apiKey, bool) {
+	value, ok := c.Get(apiKeyContextKey)
+	if !ok {
+		return nil, false
+	}
+	key, ok := value.(*apiKey)
+	return key, ok
+}
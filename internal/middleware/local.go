@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/cache"
+	"github.com/jack/golang-short-url-service/internal/model"
+)
+
+// localLimiter 是 Redis 出錯時 local 模式使用的行程內 GCRA：每個副本各自計數，
+// 額度除以 RATE_LIMIT_REPLICAS，讓所有副本加總後約等於原本的額度。
+// 每個限流對象只記一個 TAT，最多記錄 RATE_LIMIT_LOCAL_SIZE 個（LRU）。
+type localLimiter struct {
+	replicas int
+	// mu 讓讀取與寫回 TAT 成為一次原子的檢查
+	mu   sync.Mutex
+	tats *cache.LRU[time.Time]
+}
+
+func newLocalLimiter(replicas, size int) *localLimiter {
+	return &localLimiter{replicas: max(replicas, 1), tats: cache.NewLRU[time.Time](max(size, 1))}
+}
+
+// allow 與 gcraScript 相同的計算，改以本機時鐘與記憶體
+func (l *localLimiter) allow(key string, limit routeLimit) rateDecision {
+	return l.allowAt(key, limit, time.Now())
+}
+
+func (l *localLimiter) allowAt(key string, limit routeLimit, now time.Time) rateDecision {
+	requests := ceilDiv(limit.requests, l.replicas)
+	burst := ceilDiv(limit.burst, l.replicas)
+	interval := max(limit.duration/time.Duration(requests), 1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tat, ok := l.tats.Get(key)
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+
+	newTAT := tat.Add(interval)
+	allowAt := newTAT.Add(-time.Duration(burst) * interval)
+	if now.Before(allowAt) {
+		return rateDecision{limit: burst, retryAfter: allowAt.Sub(now), reset: tat}
+	}
+
+	l.tats.Set(key, newTAT, newTAT.Sub(now))
+	return rateDecision{
+		allowed:   true,
+		limit:     burst,
+		remaining: int(now.Sub(allowAt) / interval),
+		reset:     newTAT,
+	}
+}
+
+func ceilDiv(n, d int) int {
+	return max((n+d-1)/d, 1)
+}
+
+// routeState 記錄一條路由目前由 Redis 還是失敗模式處理；每次切換都記 log 並計數
+type routeState struct {
+	route string
+	// mode 是 Redis 出錯時的處理：config.FailureModeOpen、FailureModeClosed 或 FailureModeLocal
+	mode string
+
+	failing    atomic.Bool
+	switches   atomic.Int64
+	mu         sync.Mutex
+	switchedAt time.Time
+}
+
+// setFailing 在 Redis 出錯（failing=true）與恢復時切換路由狀態；狀態沒有改變時不做事
+func (s *routeState) setFailing(failing bool, err error) {
+	if !s.failing.CompareAndSwap(!failing, failing) {
+		return
+	}
+	s.switches.Add(1)
+	s.mu.Lock()
+	s.switchedAt = time.Now()
+	s.mu.Unlock()
+
+	if failing {
+		log.Printf("rate_limit mode switched: route=%s from=redis to=%s err=%v", s.route, s.mode, err)
+	} else {
+		log.Printf("rate_limit mode switched: route=%s from=%s to=redis", s.route, s.mode)
+	}
+}
+
+func (s *routeState) status() model.RateLimitRouteStatus {
+	status := model.RateLimitRouteStatus{
+		Route:       s.route,
+		FailureMode: s.mode,
+		Active:      "redis",
+		Switches:    s.switches.Load(),
+	}
+	if s.failing.Load() {
+		status.Active = s.mode
+	}
+	s.mu.Lock()
+	if !s.switchedAt.IsZero() {
+		switchedAt := s.switchedAt
+		status.SwitchedAt = &switchedAt
+	}
+	s.mu.Unlock()
+	return status
+}
+
+// routeState 回傳路由的狀態（同名路由共用，例如 shorten 與 renew 的 create）
+func (rl *RateLimiter) routeState(route string) *routeState {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if state, ok := rl.routes[route]; ok {
+		return state
+	}
+	mode, ok := rl.failureModes[route]
+	if !ok {
+		mode = rl.failureMode
+	}
+	state := &routeState{route: route, mode: mode}
+	rl.routes[route] = state
+	rl.routeOrder = append(rl.routeOrder, route)
+	return state
+}
+
+// Status 回傳本副本各路由的限流狀態（GET /api/v1/admin/ratelimit）
+func (rl *RateLimiter) Status() *model.RateLimitStatus {
+	rl.mu.Lock()
+	routes := make([]model.RateLimitRouteStatus, 0, len(rl.routeOrder))
+	for _, route := range rl.routeOrder {
+		routes = append(routes, rl.routes[route].status())
+	}
+	rl.mu.Unlock()
+
+	return &model.RateLimitStatus{
+		Algorithm: rl.algorithm,
+		Replicas:  rl.local.replicas,
+		LocalKeys: rl.local.tats.Len(),
+		Routes:    routes,
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocalLimiterAllow(t *testing.T) {
+	now := time.Now()
+	// 兩個副本：每個副本 1 分鐘 5 次，每 12 秒恢復一次
+	l := newLocalLimiter(2, 100)
+	limit := routeLimit{name: "create", requests: 10, duration: time.Minute, burst: 10}
+
+	for i := 0; i < 5; i++ {
+		d := l.allowAt("create:a", limit, now)
+		if !d.allowed || d.limit != 5 || d.remaining != 4-i {
+			t.Fatalf("request %d = %+v, want allowed with limit 5 and %d remaining", i+1, d, 4-i)
+		}
+	}
+
+	d := l.allowAt("create:a", limit, now)
+	if d.allowed {
+		t.Fatalf("request 6 = %+v, want rejected", d)
+	}
+	if d.retryAfter != 12*time.Second || !d.reset.Equal(now.Add(time.Minute)) {
+		t.Errorf("request 6 retryAfter = %v reset = %v, want 12s and %v", d.retryAfter, d.reset, now.Add(time.Minute))
+	}
+
+	if d := l.allowAt("create:b", limit, now); !d.allowed {
+		t.Errorf("other key = %+v, want allowed", d)
+	}
+	if d := l.allowAt("create:a", limit, now.Add(11*time.Second)); d.allowed {
+		t.Errorf("after 11s = %+v, want rejected", d)
+	}
+	if d := l.allowAt("create:a", limit, now.Add(12*time.Second)); !d.allowed || d.remaining != 0 {
+		t.Errorf("after 12s = %+v, want allowed with 0 remaining", d)
+	}
+}
+
+func TestLocalLimiterBurst(t *testing.T) {
+	now := time.Now()
+	l := newLocalLimiter(1, 100)
+	limit := routeLimit{name: "create", requests: 10, duration: time.Minute, burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if d := l.allowAt("create:a", limit, now); !d.allowed {
+			t.Fatalf("request %d = %+v, want allowed", i+1, d)
+		}
+	}
+	d := l.allowAt("create:a", limit, now)
+	if d.allowed || d.retryAfter != 6*time.Second {
+		t.Errorf("request 3 = %+v, want rejected for 6s", d)
+	}
+}
+
+func TestLocalLimiterReplicaShare(t *testing.T) {
+	tests := []struct {
+		replicas int
+		requests int
+		want     int
+	}{
+		{0, 10, 10},
+		{1, 10, 10},
+		{3, 10, 4},
+		{2, 3, 2},
+		{20, 10, 1},
+	}
+
+	for _, tt := range tests {
+		l := newLocalLimiter(tt.replicas, 100)
+		limit := routeLimit{name: "create", requests: tt.requests, duration: time.Minute, burst: tt.requests}
+		if d := l.allowAt("create:a", limit, time.Now()); d.limit != tt.want {
+			t.Errorf("replicas=%d requests=%d: limit = %d, want %d", tt.replicas, tt.requests, d.limit, tt.want)
+		}
+	}
+}
+
+func TestLocalLimiterEviction(t *testing.T) {
+	now := time.Now()
+	l := newLocalLimiter(1, 1)
+	limit := routeLimit{name: "create", requests: 1, duration: time.Minute, burst: 1}
+
+	if d := l.allowAt("create:a", limit, now); !d.allowed {
+		t.Fatalf("first request = %+v, want allowed", d)
+	}
+	if d := l.allowAt("create:a", limit, now); d.allowed {
+		t.Fatalf("second request = %+v, want rejected", d)
+	}
+	// 容量只有 1：b 擠掉 a 的紀錄，a 重新取得完整額度
+	if d := l.allowAt("create:b", limit, now); !d.allowed {
+		t.Fatalf("other key = %+v, want allowed", d)
+	}
+	if got := l.tats.Len(); got != 1 {
+		t.Errorf("tracked keys = %d, want 1", got)
+	}
+	if d := l.allowAt("create:a", limit, now); !d.allowed {
+		t.Errorf("evicted key = %+v, want allowed", d)
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jack/golang-short-url-service/internal/config"
+	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// quotaScript 檢查並累加每日、每月配額（UTC 日曆）；任一已用完時不累加
+//
+// KEYS[1] = 當日計數；KEYS[2] = 當月計數
+// ARGV[1] = 每日上限；ARGV[2] = 每月上限（0 表示不限，仍然計數）；ARGV[3]、ARGV[4] = 兩個 key 的到期時間（Unix 毫秒）
+// 回傳 {allowed, 當日用量, 當月用量}
+var quotaScript = redis.NewScript(`
+local daily_limit = tonumber(ARGV[1])
+local monthly_limit = tonumber(ARGV[2])
+local daily = tonumber(redis.call('GET', KEYS[1]) or '0')
+local monthly = tonumber(redis.call('GET', KEYS[2]) or '0')
+if (daily_limit > 0 and daily >= daily_limit) or (monthly_limit > 0 and monthly >= monthly_limit) then
+	return {0, daily, monthly}
+end
+
+daily = redis.call('INCR', KEYS[1])
+if daily == 1 then
+	redis.call('PEXPIREAT', KEYS[1], ARGV[3])
+end
+monthly = redis.call('INCR', KEYS[2])
+if monthly == 1 then
+	redis.call('PEXPIREAT', KEYS[2], ARGV[4])
+end
+return {1, daily, monthly}
+`)
+
+// quotaKeyGrace 讓計數 key 在週期結束後多留一段時間，容忍各副本的時鐘誤差
+const quotaKeyGrace = time.Hour
+
+// quotaPeriods 是 now 所在的日與月（UTC）
+type quotaPeriods struct {
+	dayKey, monthKey string
+	dayEnd, monthEnd time.Time
+	monthLength      time.Duration
+}
+
+func newQuotaPeriods(subject Subject, now time.Time) quotaPeriods {
+	now = now.UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+
+	prefix := "quota:" + subject.Key + ":"
+	return quotaPeriods{
+		dayKey:      prefix + "d:" + dayStart.Format("20060102"),
+		monthKey:    prefix + "m:" + monthStart.Format("200601"),
+		dayEnd:      dayStart.AddDate(0, 0, 1),
+		monthEnd:    monthEnd,
+		monthLength: monthEnd.Sub(monthStart),
+	}
+}
+
+// quotaDecision is the outcome of counting one request against the daily and monthly quotas
+type quotaDecision struct {
+	allowed        bool
+	plan           config.PlanConfig
+	periods        quotaPeriods
+	daily, monthly int64
+	// exceeded is daily or monthly when the request was rejected
+	exceeded      string
+	exceededReset time.Time
+}
+
+// consumeQuota 把一次請求計入 subject 的每日、每月配額
+func (rl *RateLimiter) consumeQuota(ctx context.Context, subject Subject, plan config.PlanConfig, now time.Time) (quotaDecision, error) {
+	periods := newQuotaPeriods(subject, now)
+	result, err := quotaScript.Run(ctx, rl.client, []string{periods.dayKey, periods.monthKey},
+		plan.DailyQuota, plan.MonthlyQuota,
+		periods.dayEnd.Add(quotaKeyGrace).UnixMilli(), periods.monthEnd.Add(quotaKeyGrace).UnixMilli(),
+	).Int64Slice()
+	if err != nil {
+		return quotaDecision{}, err
+	}
+
+	decision := quotaDecision{
+		allowed: result[0] == 1,
+		plan:    plan,
+		periods: periods,
+		daily:   result[1],
+		monthly: result[2],
+	}
+	if !decision.allowed {
+		// 兩者都用完時以較晚恢復的為準
+		if plan.MonthlyQuota > 0 && decision.monthly >= int64(plan.MonthlyQuota) {
+			decision.exceeded, decision.exceededReset = "monthly", periods.monthEnd
+		} else {
+			decision.exceeded, decision.exceededReset = "daily", periods.dayEnd
+		}
+	}
+	return decision, nil
+}
+
+// addHeaders 把有上限的配額加進 RateLimit-Policy／RateLimit
+func (d quotaDecision) addHeaders(h *rateLimitHeaders) {
+	if d.plan.DailyQuota > 0 {
+		h.add("daily", d.plan.DailyQuota, 24*time.Hour, max(d.plan.DailyQuota-int(d.daily), 0), time.Until(d.periods.dayEnd))
+	}
+	if d.plan.MonthlyQuota > 0 {
+		h.add("monthly", d.plan.MonthlyQuota, d.periods.monthLength, max(d.plan.MonthlyQuota-int(d.monthly), 0), time.Until(d.periods.monthEnd))
+	}
+}
+
+// Usage 回傳請求者的方案、各路由限流與本日／本月用量（GET /api/v1/usage）；帶了未知的 API key 時回傳 ErrInvalidAPIKey
+func (rl *RateLimiter) Usage(c *gin.Context) (*model.Usage, error) {
+	subject, err := rl.subject(c)
+	if err != nil {
+		return nil, err
+	}
+	plan := rl.plans[subject.Plan]
+	periods := newQuotaPeriods(subject, time.Now())
+
+	values, err := rl.client.MGet(c.Request.Context(), periods.dayKey, periods.monthKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	limits := make(map[string]model.RateLimitPolicy, len(plan.Limits))
+	for name := range plan.Limits {
+		limit := rl.routeLimit(plan, name)
+		policy := model.RateLimitPolicy{Requests: limit.requests, Window: limit.duration.String()}
+		if rl.algorithm == AlgorithmGCRA && limit.burst != limit.requests {
+			policy.Burst = limit.burst
+		}
+		limits[name] = policy
+	}
+
+	return &model.Usage{
+		Subject:     subject.Key,
+		Tenant:      subject.Tenant,
+		Plan:        plan.Name,
+		Limits:      limits,
+		QuotaRoutes: rl.quotaRoutes,
+		Daily:       usageQuota(plan.DailyQuota, values[0], periods.dayEnd),
+		Monthly:     usageQuota(plan.MonthlyQuota, values[1], periods.monthEnd),
+	}, nil
+}
+
+func usageQuota(limit int, value any, reset time.Time) model.UsageQuota {
+	quota := model.UsageQuota{Limit: limit, ResetAt: reset}
+	if s, ok := value.(string); ok {
+		quota.Used, _ = strconv.ParseInt(s, 10, 64)
+	}
+	if limit > 0 {
+		remaining := max(int64(limit)-quota.Used, 0)
+		quota.Remaining = &remaining
+	}
+	return quota
+}
@@ -1,69 +1,154 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jack/golang-short-url-service/internal/config"
+	"github.com/jack/golang-short-url-service/internal/repository"
 	"github.com/redis/go-redis/v9"
 )
 
-// RateLimiter implements a sliding window rate limiter using Redis
+// Rate limit algorithms (RATE_LIMIT_ALGORITHM)
+const (
+	// AlgorithmSlidingWindow 以 ZSET 記錄窗口內每一次請求（sliding log）
+	AlgorithmSlidingWindow = "sliding_window"
+	// AlgorithmGCRA 以單一 Lua 腳本原子地計算，每個 key 只存一個時間戳（見 gcra.go）
+	AlgorithmGCRA = "gcra"
+)
+
+// RateLimiter limits requests per route using Redis, with a sliding window log or GCRA.
+// Callers are identified by the key extractors (API key, tenant, IP or route) and limited by their plan.
+// When Redis fails, each route fails open, fails closed or falls back to an in-memory limiter.
 type RateLimiter struct {
-	client   *redis.Client
+	client      *redis.Client
+	algorithm   string
+	keyPrefix   string
+	plans       map[string]config.PlanConfig
+	defaultPlan string
+	extractors  []KeyExtractor
+	apiKeys     map[[32]byte]apiKey
+	quotaRoutes []string
+
+	failureMode  string
+	failureModes map[string]string
+	local        *localLimiter
+
+	mu         sync.Mutex
+	routes     map[string]*routeState
+	routeOrder []string
+}
+
+// routeLimit is a plan's limit for one route; name is the budget it counts against (the route, or default)
+type routeLimit struct {
+	name     string
 	requests int
 	duration time.Duration
+	burst    int
+}
+
+// rateDecision is the outcome of checking one request against its key's budget
+type rateDecision struct {
+	allowed   bool
+	limit     int
+	remaining int
+	// retryAfter is how long a rejected client should wait before the next request can pass
+	retryAfter time.Duration
+	// reset is when the budget is fully restored
+	reset time.Time
 }
 
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter(client *redis.Client, cfg *config.RateLimitConfig) *RateLimiter {
 	return &RateLimiter{
-		client:   client,
-		requests: cfg.Requests,
-		duration: cfg.Duration,
+		client:      client,
+		algorithm:   cfg.Algorithm,
+		keyPrefix:   "ratelimit:",
+		plans:       cfg.Plans,
+		defaultPlan: cfg.DefaultPlan,
+		extractors:  newKeyExtractors(cfg.KeyBy, cfg.DefaultPlan),
+		apiKeys:     newAPIKeys(cfg.APIKeys),
+		quotaRoutes: cfg.QuotaRoutes,
+
+		failureMode:  cfg.FailureMode,
+		failureModes: cfg.FailureModes,
+		local:        newLocalLimiter(cfg.Replicas, cfg.LocalSize),
+		routes:       make(map[string]*routeState),
 	}
 }
 
-// Middleware returns a Gin middleware for rate limiting
-func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+// Route returns a Gin middleware for rate limiting the named route (create, report, stats...).
+// Routes without their own limit in the caller's plan share the plan's default budget;
+// routes listed in RATE_LIMIT_QUOTA_ROUTES are also counted against the daily and monthly quotas.
+func (rl *RateLimiter) Route(route string) gin.HandlerFunc {
+	counted := rl.isQuotaRoute(route)
+	state := rl.routeState(route)
+
 	return func(c *gin.Context) {
-		// Get client IP
-		ip := c.ClientIP()
-		key := "ratelimit:" + ip
+		subject, err := rl.subject(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "invalid_api_key",
+				"message": "Unknown API key",
+			})
+			return
+		}
+		c.Set(subjectContextKey, subject)
 
+		plan := rl.plans[subject.Plan]
+		limit := rl.routeLimit(plan, route)
 		ctx := c.Request.Context()
+		checkQuota := counted
 
-		// Use Redis pipeline for atomic operations
-		pipe := rl.client.Pipeline()
-
-		// Get current count
-		now := time.Now().UnixNano()
-		windowStart := now - rl.duration.Nanoseconds()
-
-		// Remove old entries outside the window
-		pipe.ZRemRangeByScore(ctx, key, "0", formatInt64(windowStart))
-
-		// Count entries in the current window
-		countCmd := pipe.ZCard(ctx, key)
-
-		_, err := pipe.Exec(ctx)
-		if err != nil && err != redis.Nil {
-			// fail-open：Redis 出錯時不擋請求，但必須留下 log 方便追查
-			log.Printf("rate_limit redis error (precheck): ip=%s path=%s err=%v", ip, c.Request.URL.Path, err)
-			c.Next()
+		decision, err := rl.allow(ctx, subject, limit)
+		if err != nil && ctx.Err() != nil {
+			// 用戶端已斷線或逾時，不是 Redis 的問題：不切換失敗模式，也不必回應
+			c.Abort()
 			return
 		}
+		if err != nil {
+			// Redis 出錯時依路由的 RATE_LIMIT_FAILURE_MODE 處理；錯誤必須留下 log 方便追查（斷路器開啟期間不逐筆記錄）
+			if !errors.Is(err, repository.ErrRedisUnavailable) {
+				log.Printf("rate_limit redis error: subject=%s path=%s err=%v", subject.Key, c.Request.URL.Path, err)
+			}
+			state.setFailing(true, err)
 
-		count := countCmd.Val()
+			switch state.mode {
+			case config.FailureModeClosed:
+				c.Header("Retry-After", "5")
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"error":   "service_unavailable",
+					"message": "Rate limiting is temporarily unavailable, please retry later",
+				})
+				return
+			case config.FailureModeLocal:
+				decision = rl.local.allow(limit.name+":"+subject.Key, limit)
+				// 配額需要跨副本的計數，Redis 恢復前不檢查
+				checkQuota = false
+			default:
+				c.Next()
+				return
+			}
+		} else {
+			state.setFailing(false, nil)
+		}
 
-		// Check if rate limit exceeded
-		if count >= int64(rl.requests) {
-			c.Header("X-RateLimit-Limit", formatInt(rl.requests))
-			c.Header("X-RateLimit-Remaining", "0")
-			c.Header("X-RateLimit-Reset", formatInt64(time.Now().Add(rl.duration).Unix()))
-			c.Header("Retry-After", formatInt(int(rl.duration.Seconds())))
+		c.Header("X-RateLimit-Limit", formatInt(decision.limit))
+		c.Header("X-RateLimit-Remaining", formatInt(decision.remaining))
+		c.Header("X-RateLimit-Reset", formatInt64(ceilUnix(decision.reset)))
+
+		headers := rateLimitHeaders{}
+		headers.add(limit.name, limit.requests, limit.duration, decision.remaining, time.Until(decision.reset))
+
+		if !decision.allowed {
+			headers.set(c)
+			c.Header("Retry-After", formatInt64(ceilSeconds(decision.retryAfter)))
 
 			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 				"error":   "rate limit exceeded",
@@ -72,30 +157,140 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 			return
 		}
 
-		// Add current request to the window
-		pipe = rl.client.Pipeline()
-		pipe.ZAdd(ctx, key, redis.Z{
-			Score:  float64(now),
-			Member: now,
-		})
-		pipe.Expire(ctx, key, rl.duration)
-		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
-			// fail-open：寫入窗口失敗時不影響本次請求，但需要記錄
-			log.Printf("rate_limit redis error (record): ip=%s path=%s err=%v", ip, c.Request.URL.Path, err)
+		if checkQuota {
+			quota, err := rl.consumeQuota(ctx, subject, plan, time.Now())
+			if err != nil {
+				// 配額同樣 fail-open；用戶端斷線造成的錯誤不記錄
+				if !errors.Is(err, repository.ErrRedisUnavailable) && ctx.Err() == nil {
+					log.Printf("quota redis error: subject=%s path=%s err=%v", subject.Key, c.Request.URL.Path, err)
+				}
+			} else {
+				quota.addHeaders(&headers)
+				if !quota.allowed {
+					headers.set(c)
+					c.Header("Retry-After", formatInt64(ceilSeconds(time.Until(quota.exceededReset))))
+
+					c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+						"error":   "quota exceeded",
+						"message": "The " + quota.exceeded + " quota of the " + plan.Name + " plan is used up.",
+					})
+					return
+				}
+			}
 		}
 
-		// Set rate limit headers
-		remaining := rl.requests - int(count) - 1
-		if remaining < 0 {
-			remaining = 0
+		headers.set(c)
+		c.Next()
+	}
+}
+
+// routeLimit 回傳方案對路由的限流；gcra 的 burst 未設定時等於 requests
+func (rl *RateLimiter) routeLimit(plan config.PlanConfig, route string) routeLimit {
+	name, limit := plan.Limit(route)
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Requests
+	}
+	return routeLimit{name: name, requests: limit.Requests, duration: limit.Duration, burst: burst}
+}
+
+func (rl *RateLimiter) isQuotaRoute(route string) bool {
+	for _, r := range rl.quotaRoutes {
+		if r == route {
+			return true
 		}
+	}
+	return false
+}
 
-		c.Header("X-RateLimit-Limit", formatInt(rl.requests))
-		c.Header("X-RateLimit-Remaining", formatInt(remaining))
-		c.Header("X-RateLimit-Reset", formatInt64(time.Now().Add(rl.duration).Unix()))
+// allow 依 RATE_LIMIT_ALGORITHM 檢查並記錄一次請求；兩種演算法的 key 不同，切換時計數重新開始
+func (rl *RateLimiter) allow(ctx context.Context, subject Subject, limit routeLimit) (rateDecision, error) {
+	if rl.algorithm == AlgorithmGCRA {
+		return rl.allowGCRA(ctx, rl.keyPrefix+"gcra:"+limit.name+":"+subject.Key, limit)
+	}
+	return rl.allowSlidingWindow(ctx, rl.keyPrefix+limit.name+":"+subject.Key, limit)
+}
 
-		c.Next()
+// allowSlidingWindow 以 ZSET 記錄窗口內的請求：先計數再寫入（兩次往返，非原子，併發請求可能同時通過檢查）
+func (rl *RateLimiter) allowSlidingWindow(ctx context.Context, key string, limit routeLimit) (rateDecision, error) {
+	// Use Redis pipeline for atomic operations
+	pipe := rl.client.Pipeline()
+
+	// Get current count
+	now := time.Now().UnixNano()
+	windowStart := now - limit.duration.Nanoseconds()
+
+	// Remove old entries outside the window
+	pipe.ZRemRangeByScore(ctx, key, "0", formatInt64(windowStart))
+
+	// Count entries in the current window
+	countCmd := pipe.ZCard(ctx, key)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil && err != redis.Nil {
+		return rateDecision{}, err
+	}
+
+	count := countCmd.Val()
+	decision := rateDecision{
+		limit: limit.requests,
+		reset: time.Now().Add(limit.duration),
 	}
+
+	// Check if rate limit exceeded
+	if count >= int64(limit.requests) {
+		decision.retryAfter = limit.duration
+		return decision, nil
+	}
+
+	// Add current request to the window
+	pipe = rl.client.Pipeline()
+	pipe.ZAdd(ctx, key, redis.Z{
+		Score:  float64(now),
+		Member: now,
+	})
+	pipe.Expire(ctx, key, limit.duration)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil && !errors.Is(err, repository.ErrRedisUnavailable) {
+		// fail-open：寫入窗口失敗時不影響本次請求，但需要記錄
+		log.Printf("rate_limit redis error (record): key=%s err=%v", key, err)
+	}
+
+	decision.allowed = true
+	decision.remaining = max(limit.requests-int(count)-1, 0)
+	return decision, nil
+}
+
+// rateLimitHeaders 收集 IETF RateLimit header 草案（draft-ietf-httpapi-ratelimit-headers）的項目：
+// RateLimit-Policy: "create";q=10;w=60, "daily";q=1000;w=86400
+// RateLimit: "create";r=7;t=42, "daily";r=988;t=3600
+type rateLimitHeaders struct {
+	policies []string
+	limits   []string
+}
+
+func (h *rateLimitHeaders) add(name string, quota int, window time.Duration, remaining int, reset time.Duration) {
+	h.policies = append(h.policies, `"`+name+`";q=`+formatInt(quota)+";w="+formatInt64(ceilSeconds(window)))
+	h.limits = append(h.limits, `"`+name+`";r=`+formatInt(remaining)+";t="+formatInt64(ceilSeconds(reset)))
+}
+
+func (h *rateLimitHeaders) set(c *gin.Context) {
+	c.Header("RateLimit-Policy", strings.Join(h.policies, ", "))
+	c.Header("RateLimit", strings.Join(h.limits, ", "))
+}
+
+// ceilUnix 把時間轉成 Unix 秒（無條件進位，避免用戶端在重置前一刻重試）
+func ceilUnix(t time.Time) int64 {
+	sec := t.Unix()
+	if t.Nanosecond() > 0 {
+		sec++
+	}
+	return sec
+}
+
+// ceilSeconds 把等待時間轉成整數秒（Retry-After 只接受秒，至少 1）
+func ceilSeconds(d time.Duration) int64 {
+	sec := int64((d + time.Second - 1) / time.Second)
+	return max(sec, 1)
 }
 
 func formatInt(n int) string {
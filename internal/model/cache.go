@@ -0,0 +1,85 @@
+package model
+
+import "time"
+
+// CacheStats reports hit ratios of the URL cache tiers since process start
+type CacheStats struct {
+	// Local is the in-process LRU in front of Redis; nil when CACHE_LOCAL_SIZE is 0
+	Local *CacheTierStats `json:"local,omitempty"`
+	// Redis counts only lookups that missed the local tier
+	Redis CacheTierStats `json:"redis"`
+	// CodeFilter rejects impossible short codes before either tier; nil when CACHE_CODE_FILTER is off
+	CodeFilter *CodeFilterStats `json:"code_filter,omitempty"`
+	// Loads counts cache misses that went to PostgreSQL
+	Loads LoadStats `json:"loads"`
+}
+
+// LoadStats describes how cache misses were served
+type LoadStats struct {
+	// DBLoads is the number of PostgreSQL lookups after a cache miss
+	DBLoads int64 `json:"db_loads"`
+	// Coalesced is the number of requests that shared another request's load (singleflight)
+	Coalesced int64 `json:"coalesced"`
+	// LockWaitHits is the number of loads served from cache after waiting on another instance's lock
+	LockWaitHits int64 `json:"lock_wait_hits"`
+}
+
+// CacheTierStats is the hit/miss count of one cache tier
+type CacheTierStats struct {
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+	Size     int     `json:"size,omitempty"`
+}
+
+// NewCacheTierStats computes the hit ratio from hit and miss counts
+func NewCacheTierStats(hits, misses int64) CacheTierStats {
+	stats := CacheTierStats{Hits: hits, Misses: misses}
+	if total := hits + misses; total > 0 {
+		stats.HitRatio = float64(hits) / float64(total)
+	}
+	return stats
+}
+
+// CodeFilterStats describes the Bloom filter and negative cache for unknown short codes
+type CodeFilterStats struct {
+	// Ready is false until the Bloom filter has been built from PostgreSQL
+	Ready    bool `json:"ready"`
+	Items    int  `json:"items"`
+	Capacity int  `json:"capacity"`
+	// Watermark is the id up to which every short code is in the Bloom filter
+	Watermark int64 `json:"watermark"`
+	// MaxID is the largest created id this instance knows of; larger codes are checked against Redis
+	MaxID int64 `json:"max_id"`
+	// Rejected counts lookups answered as not found without touching Redis or PostgreSQL
+	Rejected int64           `json:"rejected"`
+	Negative *CacheTierStats `json:"negative,omitempty"`
+}
+
+// Cache warm modes
+const (
+	// CacheWarmTop preloads the most clicked links over CACHE_WARM_WINDOW
+	CacheWarmTop = "top"
+	// CacheWarmAll preloads every active, unexpired link
+	CacheWarmAll = "all"
+)
+
+// CacheWarmRequest starts a cache warm run (POST /api/v1/admin/cache/warm)
+type CacheWarmRequest struct {
+	Mode string `json:"mode"`
+	// Limit caps the number of links for mode "top" (default CACHE_WARM_TOP_N)
+	Limit int `json:"limit"`
+}
+
+// CacheWarmStatus describes the current or last cache warm run
+type CacheWarmStatus struct {
+	Mode       string     `json:"mode"`
+	Limit      int        `json:"limit,omitempty"`
+	Running    bool       `json:"running"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Loaded is the number of rows read from PostgreSQL, Cached the number written to Redis
+	Loaded int    `json:"loaded"`
+	Cached int    `json:"cached"`
+	Error  string `json:"error,omitempty"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// Health status values
+const (
+	HealthHealthy   = "healthy"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+
+	DependencyUp   = "up"
+	DependencyDown = "down"
+)
+
+// HealthResponse is the body of GET /health/detailed
+type HealthResponse struct {
+	// Status is healthy, degraded (Redis down or a breaker not closed; redirects still served) or unhealthy (PostgreSQL down)
+	Status   string           `json:"status"`
+	Postgres DependencyHealth `json:"postgres"`
+	Redis    DependencyHealth `json:"redis"`
+	// BufferedClicks is the number of click counters held in memory while Redis is unavailable
+	BufferedClicks int `json:"buffered_clicks"`
+}
+
+// DependencyHealth is the ping result and protection state of one backing store
+type DependencyHealth struct {
+	Status    string          `json:"status"`
+	LatencyMS float64         `json:"latency_ms"`
+	Error     string          `json:"error,omitempty"`
+	Breakers  []BreakerStats  `json:"breakers"`
+	Bulkheads []BulkheadStats `json:"bulkheads,omitempty"`
+}
+
+// BreakerStats is the state of one circuit breaker
+type BreakerStats struct {
+	Name string `json:"name"`
+	// State is closed, open or half_open
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`
+	Rejected int64      `json:"rejected"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+}
+
+// BulkheadStats is the concurrency limit of one PostgreSQL path
+type BulkheadStats struct {
+	Name     string `json:"name"`
+	InUse    int    `json:"in_use"`
+	Capacity int    `json:"capacity"`
+	// Rejected counts calls that found every slot busy for longer than POSTGRES_BULKHEAD_WAIT
+	Rejected int64 `json:"rejected"`
+}
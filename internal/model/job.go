@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// Job run triggers and results
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+	JobTriggerShutdown = "shutdown"
+
+	JobRunRunning   = "running"
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed"
+)
+
+// JobStatus is one background job in GET /api/v1/admin/jobs
+type JobStatus struct {
+	Name string `json:"name"`
+	// Schedule is "every <duration>", "cron <expression>" (UTC) or "manual"
+	Schedule string `json:"schedule"`
+	Jitter   string `json:"jitter,omitempty"`
+	Timeout  string `json:"timeout,omitempty"`
+	// LeaderOnly jobs run only on the replica holding the leader lease
+	LeaderOnly bool       `json:"leader_only"`
+	Running    bool       `json:"running"`
+	NextRunAt  *time.Time `json:"next_run_at,omitempty"`
+	LastRun    *JobRun    `json:"last_run,omitempty"`
+	Runs       int64      `json:"runs"`
+	Failures   int64      `json:"failures"`
+	// Skipped counts scheduled runs dropped because the previous run was still going
+	Skipped int64 `json:"skipped"`
+}
+
+// JobRun is the latest (or current) run of a job on this replica
+type JobRun struct {
+	// Trigger is schedule, manual or shutdown
+	Trigger string `json:"trigger"`
+	// Status is running, succeeded or failed
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMS float64    `json:"duration_ms"`
+	Error      string     `json:"error,omitempty"`
+}
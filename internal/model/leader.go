@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// LeaderStatus is the body of GET /api/v1/admin/leader
+type LeaderStatus struct {
+	// Name is the elected role, e.g. click-sync
+	Name string `json:"name"`
+	// Leader is the ID of the replica holding the lease; empty when there is no leader
+	Leader string `json:"leader,omitempty"`
+	// Fence is the leader's fencing token; PostgreSQL rejects click sync writes carrying an older one
+	Fence int64 `json:"fence,omitempty"`
+	// LeaseExpiresAt is when the lease expires unless the leader renews it
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	// Instance is the ID of the replica that answered
+	Instance string `json:"instance"`
+	IsLeader bool   `json:"is_leader"`
+	// LeaderSince is set when the answering replica is the leader
+	LeaderSince *time.Time `json:"leader_since,omitempty"`
+}
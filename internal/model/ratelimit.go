@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// RateLimitStatus is the body of GET /api/v1/admin/ratelimit
+type RateLimitStatus struct {
+	// Instance is the ID of the replica that answered
+	Instance  string `json:"instance"`
+	Algorithm string `json:"algorithm"`
+	// Replicas divides each limit in local mode, so the replicas together allow about the plan's limit
+	Replicas int `json:"replicas"`
+	// LocalKeys is the number of callers tracked by the in-memory limiter
+	LocalKeys int                    `json:"local_keys"`
+	Routes    []RateLimitRouteStatus `json:"routes"`
+}
+
+// RateLimitRouteStatus is the state of one rate-limited route on this replica
+type RateLimitRouteStatus struct {
+	Route string `json:"route"`
+	// FailureMode is what the route does when Redis fails: open, closed or local
+	FailureMode string `json:"failure_mode"`
+	// Active is redis, or the failure mode while Redis is failing
+	Active string `json:"active"`
+	// Switches counts changes between redis and the failure mode since process start
+	Switches   int64      `json:"switches"`
+	SwitchedAt *time.Time `json:"switched_at,omitempty"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+)
+
+// Abuse report reasons accepted by POST /api/v1/report/:code
+const (
+	ReportReasonPhishing = "phishing"
+	ReportReasonMalware  = "malware"
+	ReportReasonSpam     = "spam"
+	ReportReasonIllegal  = "illegal"
+	ReportReasonOther    = "other"
+)
+
+// Abuse report statuses
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusActioned  = "actioned"
+	ReportStatusDismissed = "dismissed"
+)
+
+// AbuseReport is a user-submitted report against a short URL
+type AbuseReport struct {
+	ID             int64      `json:"id"`
+	URLID          int64      `json:"url_id"`
+	ShortCode      string     `json:"short_code"`
+	OriginalURL    string     `json:"original_url"`
+	URLIsActive    bool       `json:"url_is_active"`
+	Reason         string     `json:"reason"`
+	Details        string     `json:"details,omitempty"`
+	ReporterEmail  string     `json:"reporter_email,omitempty"`
+	ReporterIP     string     `json:"reporter_ip,omitempty"`
+	UserAgent      string     `json:"user_agent,omitempty"`
+	Status         string     `json:"status"`
+	ResolvedBy     string     `json:"resolved_by,omitempty"`
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
+
+// CreateReportRequest is the public abuse report body
+type CreateReportRequest struct {
+	Reason string `json:"reason" binding:"required"`
+	// Details is free text describing the problem
+	Details string `json:"details,omitempty"`
+	// Email is an optional contact address for follow-up
+	Email string `json:"email,omitempty" binding:"omitempty,email"`
+}
+
+// CreateReportResponse acknowledges an abuse report
+type CreateReportResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// ResolveReportRequest is the admin body for disabling or dismissing a report
+type ResolveReportRequest struct {
+	Note string `json:"note,omitempty"`
+}
+
+// ListReportsResponse is a page of abuse reports; pass NextAfter as ?after= to get the next page
+type ListReportsResponse struct {
+	Reports   []*AbuseReport `json:"reports"`
+	NextAfter int64          `json:"next_after,omitempty"`
+}
@@ -6,15 +6,48 @@ import (
 
 // URL represents a short URL mapping
 type URL struct {
-	ID          int64      `json:"id"`
-	ShortCode   string     `json:"short_code"`
-	URLHash     string     `json:"url_hash"` // SHA256 hash for deduplication
-	OriginalURL string     `json:"original_url"`
-	ClickCount  int64      `json:"click_count"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
-	IsActive    bool       `json:"is_active"`
+	ID          int64  `json:"id"`
+	ShortCode   string `json:"short_code"`
+	URLHash     string `json:"url_hash"` // SHA256 hash for deduplication
+	OriginalURL string `json:"original_url"`
+	// CanonicalURL is the normalized form of OriginalURL used for deduplication
+	CanonicalURL string     `json:"canonical_url,omitempty"`
+	ClickCount   int64      `json:"click_count"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	IsActive     bool       `json:"is_active"`
+	// GeoTargets maps ISO 3166-1 alpha-2 country codes to destinations; OriginalURL is the fallback
+	GeoTargets map[string]string `json:"geo_targets,omitempty"`
+	// Variants splits traffic across weighted destinations (A/B tests, rotation)
+	Variants []Variant `json:"variants,omitempty"`
+	// StickyVariants keeps a returning visitor on the same variant via a cookie
+	StickyVariants bool `json:"sticky_variants,omitempty"`
+	// ForwardQuery forwards the visitor's query string to the destination
+	ForwardQuery bool `json:"forward_query,omitempty"`
+	// UTMParams are fixed utm_* parameters appended to the destination
+	UTMParams map[string]string `json:"utm_params,omitempty"`
+	// OverrideQuery lets forwarded/UTM parameters replace ones already on the destination
+	OverrideQuery bool `json:"override_query,omitempty"`
+	// Title is an optional human-readable label shown on the preview page
+	Title string `json:"title,omitempty"`
+	// AlwaysPreview forces the preview (interstitial) page instead of redirecting directly
+	AlwaysPreview bool `json:"always_preview,omitempty"`
+}
+
+// Variant is one weighted destination of a split link
+type Variant struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// VariantStats represents per-variant click statistics
+type VariantStats struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Weight     int    `json:"weight"`
+	ClickCount int64  `json:"click_count"`
 }
 
 // URLAccessLog represents an access log entry
@@ -29,26 +62,62 @@ type URLAccessLog struct {
 
 // CreateURLRequest represents the request body for creating a short URL
 type CreateURLRequest struct {
-	URL       string `json:"url" binding:"required,url"`
-	ExpiresIn string `json:"expires_in,omitempty"` // e.g., "24h", "7d"
+	URL            string            `json:"url" binding:"required,url"`
+	ExpiresIn      string            `json:"expires_in,omitempty"`      // e.g., "24h", "7d", "1w", "1mo", "1d12h"
+	ExpiresAt      *time.Time        `json:"expires_at,omitempty"`      // absolute RFC3339 expiry, instead of expires_in
+	GeoTargets     map[string]string `json:"geo_targets,omitempty"`     // e.g., {"TW": "https://example.com/tw"}
+	Variants       []Variant         `json:"variants,omitempty"`        // e.g., [{"name":"a","url":"...","weight":70}]
+	StickyVariants bool              `json:"sticky_variants,omitempty"` // keep visitors on one variant via cookie
+	ForwardQuery   bool              `json:"forward_query,omitempty"`   // forward /:code?ref=x query params
+	UTMParams      map[string]string `json:"utm_params,omitempty"`      // e.g., {"utm_source": "newsletter"}
+	OverrideQuery  bool              `json:"override_query,omitempty"`  // allow replacing params already on the destination
+	Title          string            `json:"title,omitempty"`           // shown on the preview page
+	AlwaysPreview  bool              `json:"always_preview,omitempty"`  // always show the interstitial preview page
+	// OnExpired decides what happens when the same link already exists but has expired:
+	// "renew" (default) gives the existing short code the new expiry, "new_code" archives it and mints a new code
+	OnExpired string `json:"on_expired,omitempty" binding:"omitempty,oneof=renew new_code"`
+}
+
+// CreateURLRequest.OnExpired modes
+const (
+	OnExpiredRenew   = "renew"
+	OnExpiredNewCode = "new_code"
+)
+
+// RenewURLRequest represents the request body for POST /api/v1/urls/:code/renew
+type RenewURLRequest struct {
+	ExpiresIn string     `json:"expires_in,omitempty"` // e.g., "24h", "7d"; both empty applies the default expiry
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // absolute RFC3339 expiry, instead of expires_in
 }
 
 // CreateURLResponse represents the response after creating a short URL
 type CreateURLResponse struct {
-	ShortCode   string `json:"short_code"`
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
-	ExpiresAt   string `json:"expires_at,omitempty"`
+	ShortCode   string            `json:"short_code"`
+	ShortURL    string            `json:"short_url"`
+	OriginalURL string            `json:"original_url"`
+	Title       string            `json:"title,omitempty"`
+	ExpiresAt   string            `json:"expires_at,omitempty"`
+	GeoTargets  map[string]string `json:"geo_targets,omitempty"`
+	Variants    []Variant         `json:"variants,omitempty"`
+	// Renewed is true when an expired link was renewed instead of creating a new one
+	Renewed bool `json:"renewed,omitempty"`
+	// PreviousCode is the archived short code of the expired link replaced by this one (on_expired=new_code)
+	PreviousCode string `json:"previous_code,omitempty"`
 }
 
 // URLStatsResponse represents URL statistics
 type URLStatsResponse struct {
-	ShortCode   string    `json:"short_code"`
-	OriginalURL string    `json:"original_url"`
-	ClickCount  int64     `json:"click_count"`
-	CreatedAt   time.Time `json:"created_at"`
-	ExpiresAt   string    `json:"expires_at,omitempty"`
-	IsActive    bool      `json:"is_active"`
+	ShortCode   string            `json:"short_code"`
+	OriginalURL string            `json:"original_url"`
+	Title       string            `json:"title,omitempty"`
+	ClickCount  int64             `json:"click_count"`
+	CreatedAt   time.Time         `json:"created_at"`
+	ExpiresAt   string            `json:"expires_at,omitempty"`
+	IsActive    bool              `json:"is_active"`
+	GeoTargets  map[string]string `json:"geo_targets,omitempty"`
+	Variants    []VariantStats    `json:"variants,omitempty"`
+	// SourceClicks counts clicks per tracked traffic source (e.g. "qr" for QR code scans)
+	SourceClicks map[string]int64 `json:"source_clicks,omitempty"`
 }
 
 // IsExpired checks if the URL has expired
@@ -63,3 +132,33 @@ func (u *URL) IsExpired() bool {
 func (u *URL) IsValid() bool {
 	return u.IsActive && !u.IsExpired()
 }
+
+// TotalVariantWeight returns the sum of all variant weights
+func (u *URL) TotalVariantWeight() int {
+	total := 0
+	for _, v := range u.Variants {
+		total += v.Weight
+	}
+	return total
+}
+
+// PickVariant selects a variant by weight; n must be drawn uniformly from [0, TotalVariantWeight())
+func (u *URL) PickVariant(n int) *Variant {
+	for i := range u.Variants {
+		if n < u.Variants[i].Weight {
+			return &u.Variants[i]
+		}
+		n -= u.Variants[i].Weight
+	}
+	return nil
+}
+
+// Variant returns the variant with the given name, or nil if there is none
+func (u *URL) Variant(name string) *Variant {
+	for i := range u.Variants {
+		if u.Variants[i].Name == name {
+			return &u.Variants[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Usage is the body of GET /api/v1/usage
+type Usage struct {
+	// Subject is what the caller is rate limited as, e.g. key:<id>, tenant:<name> or ip:<address>
+	Subject string `json:"subject"`
+	Tenant  string `json:"tenant,omitempty"`
+	Plan    string `json:"plan"`
+	// Limits are the plan's per-route limits; routes not listed share the default limit
+	Limits map[string]RateLimitPolicy `json:"limits"`
+	// QuotaRoutes are the routes counted against the daily and monthly quotas
+	QuotaRoutes []string   `json:"quota_routes"`
+	Daily       UsageQuota `json:"daily"`
+	Monthly     UsageQuota `json:"monthly"`
+}
+
+// RateLimitPolicy is one route limit: Requests per Window
+type RateLimitPolicy struct {
+	Requests int    `json:"requests"`
+	Window   string `json:"window"`
+	// Burst is set when the gcra algorithm allows a burst different from Requests
+	Burst int `json:"burst,omitempty"`
+}
+
+// UsageQuota is the caller's usage in the current day or month (UTC)
+type UsageQuota struct {
+	// Limit is 0 when the plan has no quota for the period
+	Limit int   `json:"limit"`
+	Used  int64 `json:"used"`
+	// Remaining is omitted when the quota is unlimited
+	Remaining *int64    `json:"remaining,omitempty"`
+	ResetAt   time.Time `json:"reset_at"`
+}
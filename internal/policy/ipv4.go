@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// parseHostIP 依瀏覽器（WHATWG URL）的規則把主機名解析成 IP：除了標準寫法，也接受 127.1、0177.0.0.1、
+// 0x7f.0.0.1、2130706433 這類 IPv4 簡寫。瀏覽器與 net/http 都會把它們連到對應的位址，
+// 只用 net.ParseIP 判斷會讓這些主機被當成一般網域名稱，繞過 IP 與私有位址的檢查。
+// 最後一段是數字但整體不是合法 IPv4 時回傳 ok=false（瀏覽器同樣視為無效的主機）。
+func parseHostIP(host string) (ip net.IP, ok bool) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, true
+	}
+	if !endsInNumber(host) {
+		return nil, true
+	}
+	ip = parseIPv4(host)
+	return ip, ip != nil
+}
+
+// endsInNumber 判斷最後一個 label 是否為數字（十進位，或 0x 開頭的十六進位）；是的話整個主機名須以 IPv4 解析
+func endsInNumber(host string) bool {
+	labels := strings.Split(host, ".")
+	last := labels[len(labels)-1]
+	if last == "" {
+		return false
+	}
+	if isDigits(last) {
+		return true
+	}
+	if len(last) >= 2 && (last[:2] == "0x" || last[:2] == "0X") {
+		_, ok := parseIPv4Part(last)
+		return ok
+	}
+	return false
+}
+
+// parseIPv4 以 WHATWG 的 IPv4 parser 解析：最多四段，每段可為十進位、0x 十六進位或 0 開頭的八進位；
+// 前面各段各佔一個 byte，最後一段填滿其餘的 byte（127.1 = 127.0.0.1）
+func parseIPv4(host string) net.IP {
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return nil
+	}
+
+	var value uint64
+	for i, part := range parts {
+		n, ok := parseIPv4Part(part)
+		if !ok {
+			return nil
+		}
+		if i < len(parts)-1 {
+			if n > 255 {
+				return nil
+			}
+			value = value<<8 | n
+			continue
+		}
+
+		remaining := uint(5 - len(parts))
+		if n >= 1<<(8*remaining) {
+			return nil
+		}
+		value = value<<(8*remaining) | n
+	}
+
+	return net.IPv4(byte(value>>24), byte(value>>16), byte(value>>8), byte(value))
+}
+
+func parseIPv4Part(part string) (uint64, bool) {
+	if part == "" {
+		return 0, false
+	}
+
+	base := 10
+	switch {
+	case len(part) >= 2 && (part[:2] == "0x" || part[:2] == "0X"):
+		part, base = part[2:], 16
+		if part == "" {
+			return 0, true
+		}
+	case len(part) > 1 && part[0] == '0':
+		part, base = part[1:], 8
+	}
+
+	n, err := strconv.ParseUint(part, base, 64)
+	return n, err == nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,280 @@
+package policy
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/config"
+	"golang.org/x/net/idna"
+)
+
+// Violation codes returned to API clients
+const (
+	CodeInvalidURL       = "invalid_url"
+	CodeDomainBlocked    = "domain_blocked"
+	CodeDomainNotAllowed = "domain_not_allowed"
+	CodeIPLiteral        = "ip_literal_not_allowed"
+	CodePrivateAddress   = "private_address_not_allowed"
+)
+
+// Violation describes why a destination was rejected by the policy engine
+type Violation struct {
+	Code string
+	Host string
+	Rule string
+}
+
+func (v *Violation) Error() string {
+	if v.Rule != "" {
+		return fmt.Sprintf("%s: %s (rule %s)", v.Code, v.Host, v.Rule)
+	}
+	return fmt.Sprintf("%s: %s", v.Code, v.Host)
+}
+
+// Engine checks destination URLs against domain blocklists/allowlists and a hot-reloaded threat-list file
+type Engine struct {
+	cfg       *config.PolicyConfig
+	blocklist *ruleSet
+	allowlist *ruleSet
+
+	mu        sync.RWMutex
+	threats   *ruleSet
+	modTime   time.Time
+	fileSize  int64
+	onReload  []func()
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+	startOnce sync.Once
+}
+
+// NewEngine creates a policy engine and loads the threat-list file if configured
+func NewEngine(cfg *config.PolicyConfig) (*Engine, error) {
+	blocklist, err := parseRules(cfg.Blocklist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid POLICY_BLOCKLIST: %w", err)
+	}
+	allowlist, err := parseRules(cfg.Allowlist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid POLICY_ALLOWLIST: %w", err)
+	}
+
+	e := &Engine{
+		cfg:       cfg,
+		blocklist: blocklist,
+		allowlist: allowlist,
+		threats:   &ruleSet{},
+		stopCh:    make(chan struct{}),
+	}
+
+	if cfg.ThreatListFile != "" {
+		if _, err := e.reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// OnReload registers a callback invoked after the threat list changes (e.g. to deactivate matching links)
+func (e *Engine) OnReload(fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onReload = append(e.onReload, fn)
+}
+
+// Start begins polling the threat-list file for changes
+func (e *Engine) Start() {
+	if e.cfg.ThreatListFile == "" || e.cfg.ReloadInterval <= 0 {
+		return
+	}
+	e.startOnce.Do(func() {
+		e.wg.Add(1)
+		go e.watch()
+		log.Printf("Policy threat list watcher started (file: %s, interval: %v)", e.cfg.ThreatListFile, e.cfg.ReloadInterval)
+	})
+}
+
+// Stop stops the threat-list watcher
+func (e *Engine) Stop() {
+	select {
+	case <-e.stopCh:
+	default:
+		close(e.stopCh)
+	}
+	e.wg.Wait()
+}
+
+// Check returns a *Violation if the destination is not allowed, nil otherwise
+func (e *Engine) Check(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return &Violation{Code: CodeInvalidURL, Host: rawURL}
+	}
+
+	host := normalizeHost(parsed.Hostname())
+
+	ip, ok := parseHostIP(host)
+	if !ok {
+		return &Violation{Code: CodeInvalidURL, Host: host}
+	}
+	if ip != nil {
+		if e.cfg.RejectIPLiterals {
+			return &Violation{Code: CodeIPLiteral, Host: host}
+		}
+		if e.cfg.RejectPrivate && isPrivateIP(ip) {
+			return &Violation{Code: CodePrivateAddress, Host: host}
+		}
+		if rule := e.matchBlocked(host, ip); rule != "" {
+			return &Violation{Code: CodeDomainBlocked, Host: host, Rule: rule}
+		}
+		if !e.allowlist.empty() && e.allowlist.match(host, ip) == "" {
+			return &Violation{Code: CodeDomainNotAllowed, Host: host}
+		}
+		return nil
+	}
+
+	if e.cfg.RejectPrivate && isPrivateHostname(host) {
+		return &Violation{Code: CodePrivateAddress, Host: host}
+	}
+	if rule := e.matchBlocked(host, nil); rule != "" {
+		return &Violation{Code: CodeDomainBlocked, Host: host, Rule: rule}
+	}
+	if !e.allowlist.empty() && e.allowlist.match(host, nil) == "" {
+		return &Violation{Code: CodeDomainNotAllowed, Host: host}
+	}
+
+	return nil
+}
+
+func (e *Engine) matchBlocked(host string, ip net.IP) string {
+	if rule := e.blocklist.match(host, ip); rule != "" {
+		return rule
+	}
+
+	e.mu.RLock()
+	threats := e.threats
+	e.mu.RUnlock()
+
+	return threats.match(host, ip)
+}
+
+func (e *Engine) watch() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.cfg.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			changed, err := e.reload()
+			if err != nil {
+				// 讀檔失敗時保留上一版規則，避免暫時性錯誤清空黑名單
+				log.Printf("policy threat list reload failed: file=%s err=%v", e.cfg.ThreatListFile, err)
+				continue
+			}
+			if changed {
+				e.mu.RLock()
+				callbacks := append([]func(){}, e.onReload...)
+				e.mu.RUnlock()
+				for _, fn := range callbacks {
+					fn()
+				}
+			}
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+// reload re-reads the threat-list file if its mtime/size changed; returns whether rules were replaced
+func (e *Engine) reload() (bool, error) {
+	info, err := os.Stat(e.cfg.ThreatListFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat threat list: %w", err)
+	}
+
+	e.mu.RLock()
+	unchanged := info.ModTime().Equal(e.modTime) && info.Size() == e.fileSize
+	e.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	rules, err := loadRuleFile(e.cfg.ThreatListFile)
+	if err != nil {
+		return false, err
+	}
+
+	e.mu.Lock()
+	e.threats = rules
+	e.modTime = info.ModTime()
+	e.fileSize = info.Size()
+	e.mu.Unlock()
+
+	log.Printf("Policy threat list loaded: file=%s rules=%d", e.cfg.ThreatListFile, rules.size())
+	return true, nil
+}
+
+// loadRuleFile parses the threat-list file format: one rule per line
+// (example.com, *.example.com, 203.0.113.7 or 203.0.113.0/24); blank lines and # comments are ignored
+func loadRuleFile(path string) (*ruleSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open threat list: %w", err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read threat list: %w", err)
+	}
+
+	rules, err := parseRules(lines)
+	if err != nil {
+		return nil, fmt.Errorf("invalid threat list %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// normalizeHost 統一成小寫並把 IDN 轉成 punycode，避免以 Unicode 網域繞過以 ASCII 撰寫的規則
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// isPrivateHostname 只看名稱本身（不做 DNS 解析，避免建立時被 DNS rebinding 繞過又拖慢請求）
+func isPrivateHostname(host string) bool {
+	if !strings.Contains(host, ".") {
+		return true // localhost、單一標籤的內網主機名
+	}
+	for _, suffix := range []string{".localhost", ".local", ".internal", ".home.arpa"} {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jack/golang-short-url-service/internal/config"
+)
+
+func TestParseHostIP(t *testing.T) {
+	tests := []struct {
+		host string
+		want string // "" 表示一般網域名稱
+		ok   bool
+	}{
+		{"127.0.0.1", "127.0.0.1", true},
+		{"127.1", "127.0.0.1", true},
+		{"10.1", "10.0.0.1", true},
+		{"192.168.257", "192.168.1.1", true},
+		{"0177.0.0.1", "127.0.0.1", true},
+		{"0x7f.0.0.1", "127.0.0.1", true},
+		{"0X7F.1", "127.0.0.1", true},
+		{"2130706433", "127.0.0.1", true},
+		{"0x7f000001", "127.0.0.1", true},
+		{"017700000001", "127.0.0.1", true},
+		{"0x", "0.0.0.0", true},
+		{"::1", "::1", true},
+		{"example.com", "", true},
+		{"1.example.com", "", true},
+		{"example.0x", "", false},
+		{"example.123", "", false},
+		{"256.0.0.1", "", false},
+		{"1.2.3.4.5", "", false},
+		{"127.0.0.256", "", false},
+		{"1.2.65536", "", false},
+		{"08.0.0.1", "", false},
+		{"0xg.1", "", false},
+		{"0xg.example", "", true},
+		{"1..1", "", false},
+	}
+
+	for _, tt := range tests {
+		ip, ok := parseHostIP(tt.host)
+		if ok != tt.ok {
+			t.Errorf("parseHostIP(%q) ok = %v, want %v", tt.host, ok, tt.ok)
+			continue
+		}
+		got := ""
+		if ip != nil {
+			got = ip.String()
+		}
+		if got != tt.want {
+			t.Errorf("parseHostIP(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCheckIPShorthand(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.PolicyConfig
+		url  string
+		code string // "" 表示允許
+	}{
+		{"shorthand loopback", config.PolicyConfig{RejectPrivate: true}, "http://127.1/", CodePrivateAddress},
+		{"octal loopback", config.PolicyConfig{RejectPrivate: true}, "http://0177.0.0.1/", CodePrivateAddress},
+		{"hex loopback", config.PolicyConfig{RejectPrivate: true}, "http://0x7f.0.0.1/", CodePrivateAddress},
+		{"shorthand private", config.PolicyConfig{RejectPrivate: true}, "http://10.1/", CodePrivateAddress},
+		{"integer loopback", config.PolicyConfig{RejectPrivate: true}, "http://2130706433/", CodePrivateAddress},
+		{"public shorthand allowed", config.PolicyConfig{RejectPrivate: true}, "http://8.8.2056/", ""},
+		{"ip literal", config.PolicyConfig{RejectIPLiterals: true}, "http://0x7f.1/", CodeIPLiteral},
+		{"decimal ip literal", config.PolicyConfig{RejectIPLiterals: true}, "http://3232235777/", CodeIPLiteral},
+		{"invalid numeric host", config.PolicyConfig{}, "http://999.1/", CodeInvalidURL},
+		{"ordinary domain", config.PolicyConfig{RejectIPLiterals: true, RejectPrivate: true}, "https://example.com/", ""},
+		{"cidr blocklist", config.PolicyConfig{Blocklist: []string{"127.0.0.0/8"}}, "http://0x7f.1/", CodeDomainBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewEngine(&tt.cfg)
+			if err != nil {
+				t.Fatalf("NewEngine: %v", err)
+			}
+			assertViolation(t, engine.Check(tt.url), tt.code)
+		})
+	}
+}
+
+func assertViolation(t *testing.T, err error, code string) {
+	t.Helper()
+	var violation *Violation
+	if code == "" {
+		if err != nil {
+			t.Errorf("Check() = %v, want allowed", err)
+		}
+		return
+	}
+	if !errors.As(err, &violation) || violation.Code != code {
+		t.Errorf("Check() = %v, want %s", err, code)
+	}
+}
@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ruleSet holds exact domains, wildcard domains (*.example.com matches the apex and every subdomain),
+// and IP/CIDR rules
+type ruleSet struct {
+	exact    map[string]bool
+	wildcard map[string]bool
+	networks []*net.IPNet
+}
+
+func parseRules(lines []string) (*ruleSet, error) {
+	rs := &ruleSet{
+		exact:    make(map[string]bool),
+		wildcard: make(map[string]bool),
+	}
+
+	for _, line := range lines {
+		rule := normalizeHost(strings.TrimSpace(line))
+		if rule == "" {
+			continue
+		}
+
+		if strings.Contains(rule, "/") {
+			_, network, err := net.ParseCIDR(rule)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR rule %q: %w", rule, err)
+			}
+			rs.networks = append(rs.networks, network)
+			continue
+		}
+
+		if ip := net.ParseIP(rule); ip != nil {
+			rs.exact[ip.String()] = true
+			continue
+		}
+
+		if strings.HasPrefix(rule, "*.") {
+			domain := rule[2:]
+			if domain == "" || strings.Contains(domain, "*") {
+				return nil, fmt.Errorf("invalid wildcard rule %q", rule)
+			}
+			rs.wildcard[domain] = true
+			continue
+		}
+
+		if strings.Contains(rule, "*") {
+			return nil, fmt.Errorf("wildcards are only supported as a leading *. in %q", rule)
+		}
+		rs.exact[rule] = true
+	}
+
+	return rs, nil
+}
+
+func (rs *ruleSet) empty() bool {
+	return rs.size() == 0
+}
+
+func (rs *ruleSet) size() int {
+	return len(rs.exact) + len(rs.wildcard) + len(rs.networks)
+}
+
+// match returns the matching rule, or "" if host matches none. ip is non-nil for IP-literal hosts.
+func (rs *ruleSet) match(host string, ip net.IP) string {
+	if ip != nil {
+		if rs.exact[ip.String()] {
+			return ip.String()
+		}
+		for _, network := range rs.networks {
+			if network.Contains(ip) {
+				return network.String()
+			}
+		}
+		return ""
+	}
+
+	if rs.exact[host] {
+		return host
+	}
+
+	// 由長到短逐層比對父網域：a.b.example.com → b.example.com → example.com → com
+	for domain := host; domain != ""; {
+		if rs.wildcard[domain] {
+			return "*." + domain
+		}
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+
+	return ""
+}
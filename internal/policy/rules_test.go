@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"net"
+	"testing"
+
+	"github.com/jack/golang-short-url-service/internal/config"
+)
+
+func TestParseRulesErrors(t *testing.T) {
+	for _, rule := range []string{"*.", "*.*.example.com", "ex*mple.com", "www.*.example.com", "10.0.0.0/33", "example.com/24"} {
+		if _, err := parseRules([]string{rule}); err == nil {
+			t.Errorf("parseRules(%q) = nil error, want an error", rule)
+		}
+	}
+
+	rs, err := parseRules([]string{"", "  ", "Example.COM.", "*.Ads.example", "10.0.0.0/8", "::1", "bücher.example"})
+	if err != nil {
+		t.Fatalf("parseRules: %v", err)
+	}
+	if rs.size() != 5 {
+		t.Errorf("size() = %d, want 5 (blank lines skipped)", rs.size())
+	}
+}
+
+func TestRuleSetMatch(t *testing.T) {
+	rs, err := parseRules([]string{"example.com", "*.ads.example", "*.tracker.test", "bücher.example", "10.0.0.0/8", "192.0.2.7", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("parseRules: %v", err)
+	}
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"example.com", "example.com"},
+		{"www.example.com", ""},
+		{"notexample.com", ""},
+		{"ads.example", "*.ads.example"},
+		{"cdn.ads.example", "*.ads.example"},
+		{"a.b.c.ads.example", "*.ads.example"},
+		{"badads.example", ""},
+		{"ads.example.org", ""},
+		{"x.tracker.test", "*.tracker.test"},
+		{"tracker.test.evil", ""},
+		{"xn--bcher-kva.example", "xn--bcher-kva.example"},
+		{"10.1.2.3", "10.0.0.0/8"},
+		{"11.1.2.3", ""},
+		{"192.0.2.7", "192.0.2.7"},
+		{"192.0.2.8", ""},
+		{"2001:db8::1", "2001:db8::/32"},
+	}
+
+	for _, tt := range tests {
+		if got := rs.match(tt.host, net.ParseIP(tt.host)); got != tt.want {
+			t.Errorf("match(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCheckLists(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.PolicyConfig
+		url  string
+		code string // "" 表示允許
+	}{
+		{"blocked exact", config.PolicyConfig{Blocklist: []string{"evil.test"}}, "https://evil.test/x", CodeDomainBlocked},
+		{"blocked case and trailing dot", config.PolicyConfig{Blocklist: []string{"evil.test"}}, "https://EVIL.test./x", CodeDomainBlocked},
+		{"exact does not cover subdomains", config.PolicyConfig{Blocklist: []string{"evil.test"}}, "https://www.evil.test/", ""},
+		{"blocked wildcard apex", config.PolicyConfig{Blocklist: []string{"*.evil.test"}}, "https://evil.test/", CodeDomainBlocked},
+		{"blocked wildcard subdomain", config.PolicyConfig{Blocklist: []string{"*.evil.test"}}, "https://a.b.evil.test/", CodeDomainBlocked},
+		{"blocked idn", config.PolicyConfig{Blocklist: []string{"bücher.example"}}, "https://BÜCHER.example/", CodeDomainBlocked},
+		{"allowlisted", config.PolicyConfig{Allowlist: []string{"*.example.com"}}, "https://docs.example.com/", ""},
+		{"allowlisted apex", config.PolicyConfig{Allowlist: []string{"*.example.com"}}, "https://example.com/", ""},
+		{"not allowlisted", config.PolicyConfig{Allowlist: []string{"*.example.com"}}, "https://example.org/", CodeDomainNotAllowed},
+		{"suffix is not a subdomain", config.PolicyConfig{Allowlist: []string{"*.example.com"}}, "https://badexample.com/", CodeDomainNotAllowed},
+		{"allowlisted cidr", config.PolicyConfig{Allowlist: []string{"203.0.113.0/24"}}, "http://203.0.113.9/", ""},
+		{"ip not allowlisted", config.PolicyConfig{Allowlist: []string{"example.com"}}, "http://203.0.113.9/", CodeDomainNotAllowed},
+		{"blocklist wins over allowlist", config.PolicyConfig{Blocklist: []string{"bad.example.com"}, Allowlist: []string{"*.example.com"}}, "https://bad.example.com/", CodeDomainBlocked},
+		{"missing host", config.PolicyConfig{}, "https:///path", CodeInvalidURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewEngine(&tt.cfg)
+			if err != nil {
+				t.Fatalf("NewEngine: %v", err)
+			}
+			assertViolation(t, engine.Check(tt.url), tt.code)
+		})
+	}
+}
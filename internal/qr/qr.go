@@ -0,0 +1,112 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Options controls how a QR code is rendered
+type Options struct {
+	Format string // png or svg
+	Size   int    // output width/height in pixels
+	Margin int    // quiet zone in modules
+	ECC    string // error correction level: L, M, Q or H
+}
+
+// Render encodes content as a QR code and returns the image bytes and its content type
+func Render(content string, opts Options) ([]byte, string, error) {
+	level, err := recoveryLevel(opts.ECC)
+	if err != nil {
+		return nil, "", err
+	}
+
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode qr code: %w", err)
+	}
+	// quiet zone 由 Margin 自行控制，不用套件固定的 4 modules
+	code.DisableBorder = true
+	modules := code.Bitmap()
+
+	switch opts.Format {
+	case FormatPNG:
+		data, err := renderPNG(modules, opts.Size, opts.Margin)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, "image/png", nil
+	case FormatSVG:
+		return renderSVG(modules, opts.Size, opts.Margin), "image/svg+xml", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported qr format: %s", opts.Format)
+	}
+}
+
+func recoveryLevel(ecc string) (qrcode.RecoveryLevel, error) {
+	switch strings.ToUpper(ecc) {
+	case "L":
+		return qrcode.Low, nil
+	case "", "M":
+		return qrcode.Medium, nil
+	case "Q":
+		return qrcode.High, nil
+	case "H":
+		return qrcode.Highest, nil
+	default:
+		return 0, fmt.Errorf("unsupported qr error correction level: %s", ecc)
+	}
+}
+
+func renderPNG(modules [][]bool, size, margin int) ([]byte, error) {
+	total := len(modules) + 2*margin
+	// 輸出尺寸至少要讓每個 module 佔 1 px，否則無法掃描
+	if size < total {
+		size = total
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y := 0; y < size; y++ {
+		my := y*total/size - margin
+		for x := 0; x < size; x++ {
+			mx := x*total/size - margin
+			if my >= 0 && my < len(modules) && mx >= 0 && mx < len(modules) && modules[my][mx] {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func renderSVG(modules [][]bool, size, margin int) []byte {
+	total := len(modules) + 2*margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, total, total)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, total, total)
+	for y, row := range modules {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d,%dh1v1h-1z", x+margin, y+margin)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
@@ -9,16 +9,34 @@ import (
 	"github.com/jack/golang-short-url-service/internal/config"
 	"github.com/jack/golang-short-url-service/internal/model"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
 	ErrURLNotFound = errors.New("url not found")
 	ErrURLExpired  = errors.New("url has expired")
+	ErrURLDisabled = errors.New("url has been disabled")
+
+	ErrReportNotFound = errors.New("abuse report not found")
+	ErrReportResolved = errors.New("abuse report already resolved")
+
+	// ErrURLHashTaken 表示 url_hash 已屬於另一筆短網址（url_hash 是 UNIQUE）
+	ErrURLHashTaken = errors.New("url hash already taken")
+
+	// ErrFenced 表示已有持有較新 fencing token 的 leader 寫入過，本副本的 leadership 已失效
+	ErrFenced = errors.New("fenced: a newer leader has taken over")
 )
 
+// clickSyncFence 是點擊同步在 leader_fences 中的名稱
+const clickSyncFence = "click_sync"
+
 type PostgresRepository struct {
 	pool *pgxpool.Pool
+
+	// reads 保護重定向的查詢（GetURLByShortCode），writes 保護建立與點擊回寫
+	reads  *dbGuard
+	writes *dbGuard
 }
 
 func NewPostgresRepository(cfg *config.PostgresConfig) (*PostgresRepository, error) {
@@ -42,59 +60,151 @@ func NewPostgresRepository(cfg *config.PostgresConfig) (*PostgresRepository, err
 		return nil, fmt.Errorf("failed to ping postgres: %w", err)
 	}
 
-	return &PostgresRepository{pool: pool}, nil
+	return &PostgresRepository{
+		pool:   pool,
+		reads:  newDBGuard("postgres_read", cfg.ReadTimeout, cfg.ReadConcurrency, cfg.BulkheadWait, cfg.BreakerThreshold, cfg.BreakerCooldown),
+		writes: newDBGuard("postgres_write", cfg.WriteTimeout, cfg.WriteConcurrency, cfg.BulkheadWait, cfg.BreakerThreshold, cfg.BreakerCooldown),
+	}, nil
 }
 
 func (r *PostgresRepository) Close() {
 	r.pool.Close()
 }
 
-// CreateURL creates a new short URL and returns the generated ID
-func (r *PostgresRepository) CreateURL(ctx context.Context, urlHash, originalURL string, expiresAt *time.Time) (*model.URL, error) {
-	query := `
-		INSERT INTO urls (short_code, url_hash, original_url, expires_at)
-		VALUES ('temp', $1, $2, $3)
-		RETURNING id, created_at, updated_at, is_active
-	`
+// urlColumns is the column list shared by every query that scans a full model.URL (see scanURL)
+const urlColumns = `id, short_code, url_hash, original_url, canonical_url, click_count, created_at, updated_at, expires_at, is_active, geo_targets, variants, sticky_variants,
+	forward_query, utm_params, override_query, title, always_preview`
 
+func scanURL(row pgx.Row) (*model.URL, error) {
 	var url model.URL
-	url.URLHash = urlHash
-	url.OriginalURL = originalURL
-	url.ExpiresAt = expiresAt
-
-	err := r.pool.QueryRow(ctx, query, urlHash, originalURL, expiresAt).Scan(
+	err := row.Scan(
 		&url.ID,
+		&url.ShortCode,
+		&url.URLHash,
+		&url.OriginalURL,
+		&url.CanonicalURL,
+		&url.ClickCount,
 		&url.CreatedAt,
 		&url.UpdatedAt,
+		&url.ExpiresAt,
 		&url.IsActive,
+		&url.GeoTargets,
+		&url.Variants,
+		&url.StickyVariants,
+		&url.ForwardQuery,
+		&url.UTMParams,
+		&url.OverrideQuery,
+		&url.Title,
+		&url.AlwaysPreview,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create url: %w", err)
+		return nil, err
 	}
 
 	return &url, nil
 }
 
-// GetURLByHash retrieves a URL by its hash (for deduplication)
-func (r *PostgresRepository) GetURLByHash(ctx context.Context, urlHash string) (*model.URL, error) {
+// CreateURL inserts a new URL row and fills in the generated ID and timestamps
+func (r *PostgresRepository) CreateURL(ctx context.Context, url *model.URL) error {
 	query := `
-		SELECT id, short_code, url_hash, original_url, click_count, created_at, updated_at, expires_at, is_active
-		FROM urls
-		WHERE url_hash = $1
+		INSERT INTO urls (
+			short_code, url_hash, original_url, canonical_url, expires_at, geo_targets, variants, sticky_variants,
+			forward_query, utm_params, override_query, title, always_preview
+		)
+		VALUES ('temp', $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at, is_active
 	`
 
-	var url model.URL
-	err := r.pool.QueryRow(ctx, query, urlHash).Scan(
-		&url.ID,
-		&url.ShortCode,
-		&url.URLHash,
-		&url.OriginalURL,
-		&url.ClickCount,
-		&url.CreatedAt,
-		&url.UpdatedAt,
-		&url.ExpiresAt,
-		&url.IsActive,
-	)
+	err := r.writes.do(ctx, func(ctx context.Context) error {
+		return r.pool.QueryRow(ctx, query,
+			url.URLHash, url.OriginalURL, url.CanonicalURL, url.ExpiresAt, url.GeoTargets, url.Variants, url.StickyVariants,
+			url.ForwardQuery, url.UTMParams, url.OverrideQuery, url.Title, url.AlwaysPreview,
+		).Scan(
+			&url.ID,
+			&url.CreatedAt,
+			&url.UpdatedAt,
+			&url.IsActive,
+		)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create url: %w", err)
+	}
+
+	return nil
+}
+
+// CreateURLWithReleasedCode inserts a new URL row that takes over the oldest short code released by the url-purge job
+// (URL_PURGE_CODES=release), other than exceptCode. Claiming the code and inserting the row share one transaction, so a
+// failed insert leaves the code free. It returns false without inserting anything when no released code is free.
+func (r *PostgresRepository) CreateURLWithReleasedCode(ctx context.Context, url *model.URL, exceptCode string) (bool, error) {
+	claim := `
+		UPDATE urls_archive SET code_reused_at = NOW()
+		WHERE id = (
+			SELECT id FROM urls_archive
+			WHERE code_released AND code_reused_at IS NULL AND short_code <> $1
+			ORDER BY archived_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING short_code
+	`
+	insert := `
+		INSERT INTO urls (
+			short_code, url_hash, original_url, canonical_url, expires_at, geo_targets, variants, sticky_variants,
+			forward_query, utm_params, override_query, title, always_preview
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, created_at, updated_at, is_active
+	`
+
+	reused := false
+	err := r.writes.do(ctx, func(ctx context.Context) error {
+		tx, err := r.pool.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		// 並行的建立各自鎖住不同的短碼（SKIP LOCKED），不會互相等待
+		var shortCode string
+		if err := tx.QueryRow(ctx, claim, exceptCode).Scan(&shortCode); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+
+		err = tx.QueryRow(ctx, insert,
+			shortCode, url.URLHash, url.OriginalURL, url.CanonicalURL, url.ExpiresAt, url.GeoTargets, url.Variants, url.StickyVariants,
+			url.ForwardQuery, url.UTMParams, url.OverrideQuery, url.Title, url.AlwaysPreview,
+		).Scan(
+			&url.ID,
+			&url.CreatedAt,
+			&url.UpdatedAt,
+			&url.IsActive,
+		)
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+		url.ShortCode = shortCode
+		reused = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to create url with released code: %w", err)
+	}
+
+	return reused, nil
+}
+
+// GetURLByHash retrieves a URL by its hash (for deduplication)
+func (r *PostgresRepository) GetURLByHash(ctx context.Context, urlHash string) (*model.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE url_hash = $1`
+
+	url, err := scanURL(r.pool.QueryRow(ctx, query, urlHash))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil // Not found, return nil without error
@@ -102,14 +212,17 @@ func (r *PostgresRepository) GetURLByHash(ctx context.Context, urlHash string) (
 		return nil, fmt.Errorf("failed to get url by hash: %w", err)
 	}
 
-	return &url, nil
+	return url, nil
 }
 
 // UpdateShortCode updates the short code for a URL
 func (r *PostgresRepository) UpdateShortCode(ctx context.Context, id int64, shortCode string) error {
 	query := `UPDATE urls SET short_code = $1 WHERE id = $2`
-	
-	_, err := r.pool.Exec(ctx, query, shortCode, id)
+
+	err := r.writes.do(ctx, func(ctx context.Context) error {
+		_, err := r.pool.Exec(ctx, query, shortCode, id)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update short code: %w", err)
 	}
@@ -119,38 +232,336 @@ func (r *PostgresRepository) UpdateShortCode(ctx context.Context, id int64, shor
 
 // GetURLByShortCode retrieves a URL by its short code
 func (r *PostgresRepository) GetURLByShortCode(ctx context.Context, shortCode string) (*model.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE short_code = $1`
+
+	var url *model.URL
+	err := r.reads.do(ctx, func(ctx context.Context) error {
+		var err error
+		url, err = scanURL(r.pool.QueryRow(ctx, query, shortCode))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrURLNotFound
+		}
+		return nil, fmt.Errorf("failed to get url: %w", err)
+	}
+
+	return url, nil
+}
+
+// ListActiveURLs pages through active URLs ordered by id (keyset pagination, pass the last seen id)
+func (r *PostgresRepository) ListActiveURLs(ctx context.Context, afterID int64, limit int) ([]*model.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE is_active AND id > $1 ORDER BY id LIMIT $2`
+
+	rows, err := r.pool.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active urls: %w", err)
+	}
+	defer rows.Close()
+
+	var urls []*model.URL
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan url: %w", err)
+		}
+		urls = append(urls, url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list active urls: %w", err)
+	}
+
+	return urls, nil
+}
+
+// ListURLs pages through every URL ordered by id, active or not (keyset pagination, pass the last seen id)
+func (r *PostgresRepository) ListURLs(ctx context.Context, afterID int64, limit int) ([]*model.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE id > $1 ORDER BY id LIMIT $2`
+
+	rows, err := r.pool.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list urls: %w", err)
+	}
+	defer rows.Close()
+
+	var urls []*model.URL
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan url: %w", err)
+		}
+		urls = append(urls, url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list urls: %w", err)
+	}
+
+	return urls, nil
+}
+
+// UpdateURLHash replaces a URL's url_hash and canonical_url, provided its hash is still oldHash.
+// It returns false when the row changed or was archived meanwhile, and ErrURLHashTaken when another URL holds urlHash.
+func (r *PostgresRepository) UpdateURLHash(ctx context.Context, id int64, oldHash, urlHash, canonicalURL string) (bool, error) {
+	query := `UPDATE urls SET url_hash = $3, canonical_url = $4 WHERE id = $1 AND url_hash = $2`
+
+	var updated bool
+	err := r.writes.do(ctx, func(ctx context.Context) error {
+		result, err := r.pool.Exec(ctx, query, id, oldHash, urlHash, canonicalURL)
+		if err != nil {
+			return err
+		}
+		updated = result.RowsAffected() > 0
+		return nil
+	})
+	if err != nil {
+		// 23505 unique_violation：只有 url_hash 被修改，衝突的必定是它
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return false, ErrURLHashTaken
+		}
+		return false, fmt.Errorf("failed to update url hash: %w", err)
+	}
+
+	return updated, nil
+}
+
+// ListTopURLs returns active, unexpired URLs ordered by clicks since the given day (most clicked first)
+func (r *PostgresRepository) ListTopURLs(ctx context.Context, since time.Time, limit int) ([]*model.URL, error) {
 	query := `
-		SELECT id, short_code, url_hash, original_url, click_count, created_at, updated_at, expires_at, is_active
+		SELECT ` + urlColumns + `
 		FROM urls
-		WHERE short_code = $1
+		JOIN (
+			SELECT url_id, SUM(click_count) AS recent_clicks
+			FROM url_daily_clicks
+			WHERE day >= $1::date
+			GROUP BY url_id
+		) recent ON recent.url_id = urls.id
+		WHERE is_active AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY recent.recent_clicks DESC
+		LIMIT $2
 	`
 
-	var url model.URL
-	err := r.pool.QueryRow(ctx, query, shortCode).Scan(
-		&url.ID,
-		&url.ShortCode,
-		&url.URLHash,
-		&url.OriginalURL,
-		&url.ClickCount,
-		&url.CreatedAt,
-		&url.UpdatedAt,
-		&url.ExpiresAt,
-		&url.IsActive,
+	rows, err := r.pool.Query(ctx, query, since.UTC().Format("2006-01-02"), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list top urls: %w", err)
+	}
+	defer rows.Close()
+
+	var urls []*model.URL
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan url: %w", err)
+		}
+		urls = append(urls, url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list top urls: %w", err)
+	}
+
+	return urls, nil
+}
+
+// ShortCodeEntry is one row loaded into the in-process short code filter
+type ShortCodeEntry struct {
+	ID        int64
+	ShortCode string
+	CreatedAt time.Time
+}
+
+// ListShortCodes pages through every URL's short code (and every archived code) ordered by id (keyset pagination, pass the last seen id)
+func (r *PostgresRepository) ListShortCodes(ctx context.Context, afterID int64, limit int) ([]ShortCodeEntry, error) {
+	// 封存後保留的短碼（tombstone）要查到才能回應 410；釋出的短碼隨時可能被新的短網址接手（id 與短碼不再對應），
+	// 兩者都必須留在短碼過濾器內
+	query := `
+		SELECT id, short_code, created_at FROM urls WHERE id > $1
+		UNION ALL
+		SELECT id, short_code, created_at FROM urls_archive WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+
+	rows, err := r.pool.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list short codes: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []ShortCodeEntry
+	for rows.Next() {
+		var entry ShortCodeEntry
+		if err := rows.Scan(&entry.ID, &entry.ShortCode, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan short code: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list short codes: %w", err)
+	}
+
+	return entries, nil
+}
+
+// MaxURLID returns the largest URL id, or 0 when the table is empty
+func (r *PostgresRepository) MaxURLID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := r.pool.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM urls`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get max url id: %w", err)
+	}
+	return id, nil
+}
+
+// DeactivateURLs sets is_active = false for the given ids and returns the number of rows changed
+func (r *PostgresRepository) DeactivateURLs(ctx context.Context, ids []int64) (int64, error) {
+	query := `UPDATE urls SET is_active = FALSE WHERE id = ANY($1) AND is_active`
+
+	result, err := r.pool.Exec(ctx, query, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to deactivate urls: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// ArchivedURL is one link moved to urls_archive
+type ArchivedURL struct {
+	ID        int64
+	ShortCode string
+	Variants  []model.Variant
+}
+
+// archiveURLsQuery moves the urls rows listed by a preceding "targets" CTE to urls_archive in one statement,
+// freeing their url_hash; $1 releases their short codes for reuse instead of keeping them as tombstones.
+// 同一個語句內所有 CTE 看到同一個快照，url_*_clicks 在 cascade 刪除前就已讀出並併入 data
+const archiveURLsQuery = `
+	moved AS (
+		DELETE FROM urls USING targets WHERE urls.id = targets.id
+		RETURNING urls.*
 	)
+	INSERT INTO urls_archive (id, short_code, url_hash, original_url, click_count, created_at, expires_at, code_released, data)
+	SELECT m.id, m.short_code, m.url_hash, m.original_url, COALESCE(m.click_count, 0), m.created_at, m.expires_at, $1,
+		to_jsonb(m) || jsonb_build_object(
+			'variant_clicks', (SELECT jsonb_object_agg(variant, click_count) FROM url_variant_clicks WHERE url_id = m.id),
+			'source_clicks', (SELECT jsonb_object_agg(source, click_count) FROM url_source_clicks WHERE url_id = m.id)
+		)
+	FROM moved m
+	RETURNING id, short_code, data->'variants'
+`
+
+// ArchiveExpiredURLs moves up to limit active links that expired before the given time from urls to urls_archive.
+// Their click rollups are folded into the archived row before the cascade deletes them. Links with open abuse
+// reports are skipped so the reports stay reviewable. Disabled links are never archived: their row is what keeps
+// a taken-down destination from being shortened again.
+// releaseCodes frees the archived short codes for reuse (CreateURLWithReleasedCode) instead of keeping them as tombstones.
+func (r *PostgresRepository) ArchiveExpiredURLs(ctx context.Context, before time.Time, limit int, releaseCodes bool) ([]ArchivedURL, error) {
+	query := `
+		WITH targets AS (
+			SELECT id FROM urls
+			WHERE is_active AND expires_at < $2
+			  AND NOT EXISTS (SELECT 1 FROM abuse_reports r WHERE r.url_id = urls.id AND r.status = 'open')
+			ORDER BY expires_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		),` + archiveURLsQuery
+
+	rows, err := r.pool.Query(ctx, query, releaseCodes, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive expired urls: %w", err)
+	}
+	defer rows.Close()
+
+	var archived []ArchivedURL
+	for rows.Next() {
+		var entry ArchivedURL
+		if err := rows.Scan(&entry.ID, &entry.ShortCode, &entry.Variants); err != nil {
+			return nil, fmt.Errorf("failed to scan archived url: %w", err)
+		}
+		archived = append(archived, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to archive expired urls: %w", err)
+	}
+
+	return archived, nil
+}
+
+// ArchiveURL moves one expired link from urls to urls_archive (the predecessor of a re-created link).
+// Like ArchiveExpiredURLs it skips links with open abuse reports, and it re-checks the expiry under the row lock
+// so a link renewed concurrently is kept; in both cases, or when the link is gone or disabled, it returns ErrURLNotFound.
+func (r *PostgresRepository) ArchiveURL(ctx context.Context, id int64, releaseCode bool) (*ArchivedURL, error) {
+	query := `
+		WITH targets AS (
+			SELECT id FROM urls
+			WHERE id = $2 AND is_active AND expires_at <= NOW()
+			  AND NOT EXISTS (SELECT 1 FROM abuse_reports r WHERE r.url_id = urls.id AND r.status = 'open')
+			FOR UPDATE
+		),` + archiveURLsQuery
+
+	var archived ArchivedURL
+	err := r.writes.do(ctx, func(ctx context.Context) error {
+		return r.pool.QueryRow(ctx, query, releaseCode, id).Scan(&archived.ID, &archived.ShortCode, &archived.Variants)
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrURLNotFound
 		}
-		return nil, fmt.Errorf("failed to get url: %w", err)
+		return nil, fmt.Errorf("failed to archive url: %w", err)
 	}
 
-	return &url, nil
+	return &archived, nil
+}
+
+// RenewURL sets a new expiry on an active (typically expired) link and returns the updated row. Renewal never
+// shortens a link's lifetime: a link that never expires keeps no expiry, and a later current expiry is kept.
+// Disabled links are not renewed (ErrURLNotFound).
+func (r *PostgresRepository) RenewURL(ctx context.Context, id int64, expiresAt *time.Time) (*model.URL, error) {
+	query := `
+		UPDATE urls SET expires_at = CASE
+			WHEN expires_at IS NULL OR $2::timestamptz IS NULL THEN NULL
+			ELSE GREATEST(expires_at, $2::timestamptz)
+		END
+		WHERE id = $1 AND is_active
+		RETURNING ` + urlColumns
+
+	var url *model.URL
+	err := r.writes.do(ctx, func(ctx context.Context) error {
+		var err error
+		url, err = scanURL(r.pool.QueryRow(ctx, query, id, expiresAt))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrURLNotFound
+		}
+		return nil, fmt.Errorf("failed to renew url: %w", err)
+	}
+
+	return url, nil
+}
+
+// ArchivedShortCode reports whether the short code belongs to an archived link, either kept as a tombstone
+// or released for reuse
+func (r *PostgresRepository) ArchivedShortCode(ctx context.Context, shortCode string) (tombstoned, released bool, err error) {
+	query := `
+		SELECT COALESCE(bool_or(NOT code_released), FALSE), COALESCE(bool_or(code_released), FALSE)
+		FROM urls_archive WHERE short_code = $1
+	`
+
+	err = r.reads.do(ctx, func(ctx context.Context) error {
+		return r.pool.QueryRow(ctx, query, shortCode).Scan(&tombstoned, &released)
+	})
+	if err != nil {
+		return false, false, fmt.Errorf("failed to check archived short code: %w", err)
+	}
+
+	return tombstoned, released, nil
 }
 
 // IncrementClickCount increments the click count for a URL by 1
 func (r *PostgresRepository) IncrementClickCount(ctx context.Context, id int64) error {
 	query := `UPDATE urls SET click_count = click_count + 1 WHERE id = $1`
-	
+
 	_, err := r.pool.Exec(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to increment click count: %w", err)
@@ -161,13 +572,48 @@ func (r *PostgresRepository) IncrementClickCount(ctx context.Context, id int64)
 
 // IncrementClickCountBy increments the click count for a URL by a specified amount (used for batch sync)
 func (r *PostgresRepository) IncrementClickCountBy(ctx context.Context, shortCode string, count int64) error {
-	query := `UPDATE urls SET click_count = click_count + $1 WHERE short_code = $2`
-	
-	result, err := r.pool.Exec(ctx, query, count, shortCode)
+	// 同時累加當日的 url_daily_clicks，供「近期熱門」查詢使用
+	query := `
+		WITH updated AS (
+			UPDATE urls SET click_count = click_count + $1 WHERE short_code = $2 RETURNING id
+		)
+		INSERT INTO url_daily_clicks (url_id, day, click_count)
+		SELECT id, (NOW() AT TIME ZONE 'UTC')::date, $1 FROM updated
+		ON CONFLICT (url_id, day) DO UPDATE
+		SET click_count = url_daily_clicks.click_count + EXCLUDED.click_count
+	`
+
+	var rows int64
+	err := r.writes.do(ctx, func(ctx context.Context) error {
+		result, err := r.pool.Exec(ctx, query, count, shortCode)
+		rows = result.RowsAffected()
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to increment click count by %d: %w", count, err)
 	}
 
+	if rows == 0 {
+		return ErrURLNotFound
+	}
+
+	return nil
+}
+
+// IncrementVariantClickCountBy adds count to a variant's click counter (used for batch sync)
+func (r *PostgresRepository) IncrementVariantClickCountBy(ctx context.Context, shortCode, variant string, count int64) error {
+	query := `
+		INSERT INTO url_variant_clicks (url_id, variant, click_count)
+		SELECT id, $2, $3 FROM urls WHERE short_code = $1
+		ON CONFLICT (url_id, variant) DO UPDATE
+		SET click_count = url_variant_clicks.click_count + EXCLUDED.click_count, updated_at = NOW()
+	`
+
+	result, err := r.pool.Exec(ctx, query, shortCode, variant, count)
+	if err != nil {
+		return fmt.Errorf("failed to increment variant click count by %d: %w", count, err)
+	}
+
 	if result.RowsAffected() == 0 {
 		return ErrURLNotFound
 	}
@@ -175,6 +621,226 @@ func (r *PostgresRepository) IncrementClickCountBy(ctx context.Context, shortCod
 	return nil
 }
 
+// ApplyClickCounts adds a staged batch of counters in one transaction, with one set-based statement
+// per table (UPDATE … FROM unnest). The run ID is recorded in click_sync_runs in the same transaction:
+// it returns false without changing anything when the run was already applied, so replaying a run is safe.
+// A non-zero fence is the caller's leader fencing token: the transaction fails with ErrFenced when a newer
+// leader has already applied a run. Counters of short codes that no longer exist are ignored.
+func (r *PostgresRepository) ApplyClickCounts(ctx context.Context, runID string, fence int64, counts map[ClickCountKey]int64) (bool, error) {
+	var codes, variantCodes, variants, sourceCodes, sources []string
+	var totals, variantCounts, sourceCounts []int64
+	for key, n := range counts {
+		switch {
+		case key.Variant != "":
+			variantCodes = append(variantCodes, key.ShortCode)
+			variants = append(variants, key.Variant)
+			variantCounts = append(variantCounts, n)
+		case key.Source != "":
+			sourceCodes = append(sourceCodes, key.ShortCode)
+			sources = append(sources, key.Source)
+			sourceCounts = append(sourceCounts, n)
+		default:
+			codes = append(codes, key.ShortCode)
+			totals = append(totals, n)
+		}
+	}
+
+	// 每個 key 在 map 內只出現一次，UPDATE … FROM 與 ON CONFLICT 都不會碰到同一列兩次
+	totalsQuery := `
+		WITH updated AS (
+			UPDATE urls SET click_count = urls.click_count + v.n
+			FROM unnest($1::text[], $2::bigint[]) AS v(short_code, n)
+			WHERE urls.short_code = v.short_code
+			RETURNING urls.id, v.n
+		)
+		INSERT INTO url_daily_clicks (url_id, day, click_count)
+		SELECT id, (NOW() AT TIME ZONE 'UTC')::date, n FROM updated
+		ON CONFLICT (url_id, day) DO UPDATE
+		SET click_count = url_daily_clicks.click_count + EXCLUDED.click_count
+	`
+	variantsQuery := `
+		INSERT INTO url_variant_clicks (url_id, variant, click_count)
+		SELECT u.id, v.variant, v.n
+		FROM unnest($1::text[], $2::text[], $3::bigint[]) AS v(short_code, variant, n)
+		JOIN urls u ON u.short_code = v.short_code
+		ON CONFLICT (url_id, variant) DO UPDATE
+		SET click_count = url_variant_clicks.click_count + EXCLUDED.click_count, updated_at = NOW()
+	`
+	sourcesQuery := `
+		INSERT INTO url_source_clicks (url_id, source, click_count)
+		SELECT u.id, v.source, v.n
+		FROM unnest($1::text[], $2::text[], $3::bigint[]) AS v(short_code, source, n)
+		JOIN urls u ON u.short_code = v.short_code
+		ON CONFLICT (url_id, source) DO UPDATE
+		SET click_count = url_source_clicks.click_count + EXCLUDED.click_count, updated_at = NOW()
+	`
+
+	var clicks int64
+	for _, n := range counts {
+		clicks += n
+	}
+
+	applied := false
+	err := r.writes.do(ctx, func(ctx context.Context) error {
+		tx, err := r.pool.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		// 舊 leader（lease 已過期但自己還不知道）在這裡被擋下；列鎖也讓新舊 leader 的交易不會交錯
+		if fence > 0 {
+			tag, err := tx.Exec(ctx, `
+				INSERT INTO leader_fences (name, token) VALUES ($1, $2)
+				ON CONFLICT (name) DO UPDATE SET token = EXCLUDED.token, updated_at = NOW()
+				WHERE leader_fences.token <= EXCLUDED.token
+			`, clickSyncFence, fence)
+			if err != nil {
+				return err
+			}
+			if tag.RowsAffected() == 0 {
+				return ErrFenced
+			}
+		}
+
+		// 同一個 run 並行重播時，後到的交易會等先到的提交，再因主鍵衝突而不寫入
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO click_sync_runs (run_id, keys, clicks) VALUES ($1, $2, $3)
+			ON CONFLICT (run_id) DO NOTHING
+		`, runID, len(counts), clicks)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		if len(codes) > 0 {
+			if _, err := tx.Exec(ctx, totalsQuery, codes, totals); err != nil {
+				return err
+			}
+		}
+		if len(variantCodes) > 0 {
+			if _, err := tx.Exec(ctx, variantsQuery, variantCodes, variants, variantCounts); err != nil {
+				return err
+			}
+		}
+		if len(sourceCodes) > 0 {
+			if _, err := tx.Exec(ctx, sourcesQuery, sourceCodes, sources, sourceCounts); err != nil {
+				return err
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to apply click counts: %w", err)
+	}
+
+	return applied, nil
+}
+
+// ClickSyncFence returns the highest fencing token that has applied a click sync run (0 if none)
+func (r *PostgresRepository) ClickSyncFence(ctx context.Context) (int64, error) {
+	var token int64
+	err := r.pool.QueryRow(ctx, `SELECT token FROM leader_fences WHERE name = $1`, clickSyncFence).Scan(&token)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get click sync fence: %w", err)
+	}
+	return token, nil
+}
+
+// PruneClickSyncRuns deletes ledger rows of runs applied before the given time
+func (r *PostgresRepository) PruneClickSyncRuns(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := r.writes.do(ctx, func(ctx context.Context) error {
+		tag, err := r.pool.Exec(ctx, `DELETE FROM click_sync_runs WHERE applied_at < $1`, before)
+		deleted = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune click sync runs: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// GetVariantClickCounts returns the synced click count of every variant of a URL
+func (r *PostgresRepository) GetVariantClickCounts(ctx context.Context, urlID int64) (map[string]int64, error) {
+	query := `SELECT variant, click_count FROM url_variant_clicks WHERE url_id = $1`
+
+	rows, err := r.pool.Query(ctx, query, urlID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variant click counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var variant string
+		var count int64
+		if err := rows.Scan(&variant, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan variant click count: %w", err)
+		}
+		counts[variant] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get variant click counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// IncrementSourceClickCountBy adds count to a traffic source's click counter (used for batch sync)
+func (r *PostgresRepository) IncrementSourceClickCountBy(ctx context.Context, shortCode, source string, count int64) error {
+	query := `
+		INSERT INTO url_source_clicks (url_id, source, click_count)
+		SELECT id, $2, $3 FROM urls WHERE short_code = $1
+		ON CONFLICT (url_id, source) DO UPDATE
+		SET click_count = url_source_clicks.click_count + EXCLUDED.click_count, updated_at = NOW()
+	`
+
+	result, err := r.pool.Exec(ctx, query, shortCode, source, count)
+	if err != nil {
+		return fmt.Errorf("failed to increment source click count by %d: %w", count, err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrURLNotFound
+	}
+
+	return nil
+}
+
+// GetSourceClickCounts returns the synced click count of every traffic source of a URL
+func (r *PostgresRepository) GetSourceClickCounts(ctx context.Context, urlID int64) (map[string]int64, error) {
+	query := `SELECT source, click_count FROM url_source_clicks WHERE url_id = $1`
+
+	rows, err := r.pool.Query(ctx, query, urlID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source click counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var source string
+		var count int64
+		if err := rows.Scan(&source, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan source click count: %w", err)
+		}
+		counts[source] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get source click counts: %w", err)
+	}
+
+	return counts, nil
+}
+
 // LogAccess logs an access to a URL
 func (r *PostgresRepository) LogAccess(ctx context.Context, log *model.URLAccessLog) error {
 	query := `
@@ -190,6 +856,158 @@ func (r *PostgresRepository) LogAccess(ctx context.Context, log *model.URLAccess
 	return nil
 }
 
+// reportColumns is the column list shared by every query that scans a full model.AbuseReport (see scanReport)
+const reportColumns = `r.id, r.url_id, u.short_code, u.original_url, u.is_active, r.reason, r.details, r.reporter_email,
+	COALESCE(host(r.reporter_ip), ''), r.user_agent, r.status, r.resolved_by, r.resolution_note, r.created_at, r.resolved_at`
+
+func scanReport(row pgx.Row) (*model.AbuseReport, error) {
+	var report model.AbuseReport
+	err := row.Scan(
+		&report.ID,
+		&report.URLID,
+		&report.ShortCode,
+		&report.OriginalURL,
+		&report.URLIsActive,
+		&report.Reason,
+		&report.Details,
+		&report.ReporterEmail,
+		&report.ReporterIP,
+		&report.UserAgent,
+		&report.Status,
+		&report.ResolvedBy,
+		&report.ResolutionNote,
+		&report.CreatedAt,
+		&report.ResolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// CreateAbuseReport stores a report; returns false if the reporter already has an open report for the URL
+func (r *PostgresRepository) CreateAbuseReport(ctx context.Context, report *model.AbuseReport) (bool, error) {
+	query := `
+		INSERT INTO abuse_reports (url_id, reason, details, reporter_email, reporter_ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (url_id, reporter_ip) WHERE status = 'open' DO NOTHING
+		RETURNING id, status, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		report.URLID, report.Reason, report.Details, report.ReporterEmail, report.ReporterIP, report.UserAgent,
+	).Scan(
+		&report.ID,
+		&report.Status,
+		&report.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create abuse report: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetAbuseReport retrieves a report together with its short URL
+func (r *PostgresRepository) GetAbuseReport(ctx context.Context, id int64) (*model.AbuseReport, error) {
+	query := `SELECT ` + reportColumns + ` FROM abuse_reports r JOIN urls u ON u.id = r.url_id WHERE r.id = $1`
+
+	report, err := scanReport(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrReportNotFound
+		}
+		return nil, fmt.Errorf("failed to get abuse report: %w", err)
+	}
+
+	return report, nil
+}
+
+// ListAbuseReports pages through reports ordered by id (keyset pagination); an empty status lists every status
+func (r *PostgresRepository) ListAbuseReports(ctx context.Context, status string, afterID int64, limit int) ([]*model.AbuseReport, error) {
+	query := `
+		SELECT ` + reportColumns + `
+		FROM abuse_reports r JOIN urls u ON u.id = r.url_id
+		WHERE ($1 = '' OR r.status = $1) AND r.id > $2
+		ORDER BY r.id
+		LIMIT $3
+	`
+
+	rows, err := r.pool.Query(ctx, query, status, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list abuse reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []*model.AbuseReport{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan abuse report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list abuse reports: %w", err)
+	}
+
+	return reports, nil
+}
+
+// ResolveAbuseReport closes an open report with the given status. When status is actioned the reported URL
+// is deactivated and every other open report for it is closed too, all in one transaction.
+func (r *PostgresRepository) ResolveAbuseReport(ctx context.Context, id int64, status, resolvedBy, note string) (*model.AbuseReport, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var urlID int64
+	var current string
+	err = tx.QueryRow(ctx, `SELECT url_id, status FROM abuse_reports WHERE id = $1 FOR UPDATE`, id).Scan(&urlID, &current)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrReportNotFound
+		}
+		return nil, fmt.Errorf("failed to lock abuse report: %w", err)
+	}
+	if current != model.ReportStatusOpen {
+		return nil, ErrReportResolved
+	}
+
+	// 停用時同一短網址的其他 open 檢舉一併結案；駁回只影響這一筆
+	closeOthers := status == model.ReportStatusActioned
+	if closeOthers {
+		if _, err := tx.Exec(ctx, `UPDATE urls SET is_active = FALSE WHERE id = $1 AND is_active`, urlID); err != nil {
+			return nil, fmt.Errorf("failed to deactivate url: %w", err)
+		}
+	}
+
+	update := `
+		UPDATE abuse_reports SET status = $1, resolved_by = $2, resolution_note = $3, resolved_at = NOW()
+		WHERE id = $4 OR ($5 AND url_id = $6 AND status = 'open')
+	`
+	if _, err := tx.Exec(ctx, update, status, resolvedBy, note, id, closeOthers, urlID); err != nil {
+		return nil, fmt.Errorf("failed to resolve abuse report: %w", err)
+	}
+
+	query := `SELECT ` + reportColumns + ` FROM abuse_reports r JOIN urls u ON u.id = r.url_id WHERE r.id = $1`
+	report, err := scanReport(tx.QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get abuse report: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return report, nil
+}
+
 // GetURLStats retrieves statistics for a URL
 func (r *PostgresRepository) GetURLStats(ctx context.Context, shortCode string) (*model.URL, error) {
 	return r.GetURLByShortCode(ctx, shortCode)
@@ -199,4 +1017,3 @@ func (r *PostgresRepository) GetURLStats(ctx context.Context, shortCode string)
 func (r *PostgresRepository) Health(ctx context.Context) error {
 	return r.pool.Ping(ctx)
 }
-
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/breaker"
+	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrDBUnavailable 表示 PostgreSQL 斷路器開啟中，查詢沒有送出
+	ErrDBUnavailable = fmt.Errorf("postgres unavailable: %w", breaker.ErrOpen)
+	// ErrDBBusy 表示該路徑的併發上限（bulkhead）已滿，等待 POSTGRES_BULKHEAD_WAIT 後仍沒有空位
+	ErrDBBusy = errors.New("postgres busy: too many concurrent queries")
+)
+
+// dbGuard 保護一條 PostgreSQL 路徑（讀或寫）：先佔 bulkhead 名額，再經過斷路器，查詢本身套用逾時。
+// 讀寫分開，寫入變慢時不會佔光重定向需要的連線。
+type dbGuard struct {
+	name     string
+	timeout  time.Duration
+	wait     time.Duration
+	slots    chan struct{}
+	breaker  *breaker.Breaker
+	rejected atomic.Int64
+}
+
+func newDBGuard(name string, timeout time.Duration, concurrency int, wait time.Duration, threshold int, cooldown time.Duration) *dbGuard {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if cooldown <= 0 {
+		cooldown = 10 * time.Second
+	}
+	return &dbGuard{
+		name:    name,
+		timeout: timeout,
+		wait:    wait,
+		slots:   make(chan struct{}, concurrency),
+		breaker: breaker.New(name, threshold, cooldown),
+	}
+}
+
+func (g *dbGuard) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := g.acquire(ctx); err != nil {
+		return err
+	}
+	defer func() { <-g.slots }()
+
+	// 以呼叫端的 ctx 判斷：呼叫端取消或自己逾時不算 PostgreSQL 失敗，只有 guard 自己的逾時才算
+	err := g.breaker.DoContext(ctx, func() error {
+		queryCtx := ctx
+		if g.timeout > 0 {
+			var cancel context.CancelFunc
+			queryCtx, cancel = context.WithTimeout(ctx, g.timeout)
+			defer cancel()
+		}
+		return fn(queryCtx)
+	}, isDBFailure)
+	if err == breaker.ErrOpen {
+		return ErrDBUnavailable
+	}
+	return err
+}
+
+func (g *dbGuard) acquire(ctx context.Context) error {
+	select {
+	case g.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(g.wait)
+	defer timer.Stop()
+	select {
+	case g.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		g.rejected.Add(1)
+		return ErrDBBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *dbGuard) bulkheadStats() model.BulkheadStats {
+	return model.BulkheadStats{
+		Name:     g.name,
+		InUse:    len(g.slots),
+		Capacity: cap(g.slots),
+		Rejected: g.rejected.Load(),
+	}
+}
+
+// isDBFailure 只把連線、逾時與資料庫本身無法服務的錯誤算進斷路器；查無資料、違反約束等是正常回應。
+func isDBFailure(err error) bool {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) || errors.Is(err, ErrURLNotFound) || errors.Is(err, ErrFenced) {
+		return false
+	}
+	// 取消只會來自呼叫端（guard 的逾時是 DeadlineExceeded）
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// 08 連線、53 資源不足、57 管理者介入/statement_timeout、58 系統錯誤
+		switch pgErr.Code[:2] {
+		case "08", "53", "57", "58":
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// newBreakerStats 轉成對外的斷路器狀態
+func newBreakerStats(stats breaker.Stats) model.BreakerStats {
+	result := model.BreakerStats{
+		Name:     stats.Name,
+		State:    stats.State.String(),
+		Failures: stats.Failures,
+		Rejected: stats.Rejected,
+	}
+	if !stats.OpenedAt.IsZero() {
+		openedAt := stats.OpenedAt
+		result.OpenedAt = &openedAt
+	}
+	return result
+}
+
+// Breakers 回傳讀、寫路徑斷路器的狀態
+func (r *PostgresRepository) Breakers() []model.BreakerStats {
+	return []model.BreakerStats{
+		newBreakerStats(r.reads.breaker.Stats()),
+		newBreakerStats(r.writes.breaker.Stats()),
+	}
+}
+
+// Bulkheads 回傳讀、寫路徑的併發使用量
+func (r *PostgresRepository) Bulkheads() []model.BulkheadStats {
+	return []model.BulkheadStats{r.reads.bulkheadStats(), r.writes.bulkheadStats()}
+}
@@ -2,10 +2,20 @@ package repository
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/jack/golang-short-url-service/internal/breaker"
+	"github.com/jack/golang-short-url-service/internal/cache"
 	"github.com/jack/golang-short-url-service/internal/config"
 	"github.com/jack/golang-short-url-service/internal/model"
 	"github.com/redis/go-redis/v9"
@@ -15,31 +25,154 @@ const (
 	urlCachePrefix   = "url:"
 	clickCountPrefix = "clicks:"
 	urlCacheTTL      = 1 * time.Hour
+
+	// variant / 來源點擊計數是 clicks: 底下的子 key：clicks:<code>:variant:<name>、clicks:<code>:source:<name>
+	variantClickInfix = ":variant:"
+	sourceClickInfix  = ":source:"
+
+	// pendingClicksPrefix 是點擊同步的暫存 hash：clicks:pending:<runID>，寫入 PostgreSQL 後才刪除
+	pendingClicksPrefix = clickCountPrefix + "pending:"
+
+	// lockPrefix 是分散式鎖的 key 前綴：lock:<name>
+	lockPrefix = "lock:"
+
+	// maxURLIDKey 記錄已建立完成的最大短網址 id，短碼過濾器用來判斷「尚未存在」的短碼
+	maxURLIDKey = "meta:url_max_id"
+
+	// urlInvalidationChannel 廣播 url: 快取失效，訊息格式為 <instanceID>:<shortCode>
+	urlInvalidationChannel = "url:invalidate"
 )
 
+// raiseMaxScript 只在新值較大時更新，避免並行建立時較小的 id 覆蓋掉較大的
+var raiseMaxScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if tonumber(ARGV[1]) > current then
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// releaseLockScript 只刪除自己持有的鎖（token 相符），避免鎖過期後誤刪別人剛取得的鎖
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// stageCountersScript 把 KEYS[2..] 的計數器以 GETDEL 取出，HINCRBY 到暫存 hash KEYS[1]（field 為原本的 key）。
+// 整批在 Redis 內原子完成：計數不是在 clicks:* 就是在暫存 hash，不會有只存在於同步行程記憶體中的時刻。
+// 非字串（例如其他批次的暫存 hash）與非整數的 key 會略過；回傳 {搬移數, 非整數數}。
+var stageCountersScript = redis.NewScript(`
+local moved, invalid = 0, 0
+for i = 2, #KEYS do
+	if redis.call('TYPE', KEYS[i]).ok == 'string' then
+		local value = redis.call('GET', KEYS[i])
+		if string.match(value, '^-?%d+$') then
+			redis.call('DEL', KEYS[i])
+			redis.call('HINCRBY', KEYS[1], KEYS[i], value)
+			moved = moved + 1
+		else
+			invalid = invalid + 1
+		end
+	end
+end
+return {moved, invalid}
+`)
+
 type RedisRepository struct {
 	client *redis.Client
+
+	// local 是 Redis 前面的行程內 LRU（nil 表示停用）；各副本透過 pub/sub 同步失效
+	local      *cache.LRU[*model.URL]
+	localTTL   time.Duration
+	instanceID string
+	pubsub     *redis.PubSub
+	wg         sync.WaitGroup
+
+	redisHits   atomic.Int64
+	redisMisses atomic.Int64
+
+	// breaker 開啟時所有指令直接回 ErrRedisUnavailable，不再逐一等逾時
+	breaker      *breaker.Breaker
+	stopCh       chan struct{}
+	reconnectMu  sync.Mutex
+	reconnectFns []func()
+
+	pendingMu      sync.Mutex
+	pendingDeletes map[string]struct{}
 }
 
-func NewRedisRepository(cfg *config.RedisConfig) (*RedisRepository, error) {
+// NewRedisRepository 連不上 Redis 時不會失敗：斷路器直接開啟，服務改由 PostgreSQL 提供，
+// 背景每 REDIS_BREAKER_COOLDOWN 探測一次，Redis 恢復後自動接回。
+func NewRedisRepository(cfg *config.RedisConfig, cacheCfg *config.CacheConfig) (*RedisRepository, error) {
 	client := redis.NewClient(&redis.Options{
-		Addr:     cfg.Addr(),
-		Password: cfg.Password,
-		DB:       cfg.DB,
-		PoolSize: cfg.PoolSize,
+		Addr:         cfg.Addr(),
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		PoolSize:     cfg.PoolSize,
+		DialTimeout:  cfg.DialTimeout,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
 	})
 
+	cooldown := cfg.BreakerCooldown
+	if cooldown <= 0 {
+		cooldown = 10 * time.Second
+	}
+
+	r := &RedisRepository{
+		client:         client,
+		breaker:        breaker.New("redis", cfg.BreakerThreshold, cooldown),
+		stopCh:         make(chan struct{}),
+		pendingDeletes: make(map[string]struct{}),
+	}
+	r.breaker.OnStateChange(r.breakerChanged)
+	client.AddHook(breakerHook{breaker: r.breaker})
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to ping redis: %w", err)
+		log.Printf("failed to ping redis, starting without cache: addr=%s err=%v", cfg.Addr(), err)
+		r.breaker.Trip()
+	}
+
+	if cacheCfg.LocalSize > 0 && cacheCfg.LocalTTL > 0 {
+		if err := r.enableLocalCache(cacheCfg.LocalSize, cacheCfg.LocalTTL); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	r.wg.Add(1)
+	go r.monitor(cooldown)
+
+	return r, nil
+}
+
+func (r *RedisRepository) enableLocalCache(size int, ttl time.Duration) error {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("failed to generate cache instance id: %w", err)
 	}
 
-	return &RedisRepository{client: client}, nil
+	r.local = cache.NewLRU[*model.URL](size)
+	r.localTTL = ttl
+	r.instanceID = hex.EncodeToString(id)
+	r.pubsub = r.client.Subscribe(context.Background(), urlInvalidationChannel)
+
+	r.wg.Add(1)
+	go r.listenInvalidations()
+	return nil
 }
 
 func (r *RedisRepository) Close() error {
+	close(r.stopCh)
+	if r.pubsub != nil {
+		r.pubsub.Close()
+	}
+	r.wg.Wait()
 	return r.client.Close()
 }
 
@@ -47,26 +180,40 @@ func (r *RedisRepository) Client() *redis.Client {
 	return r.client
 }
 
+// GetURL 先查行程內 LRU，再查 Redis（並回填 LRU）；兩層都沒有時回傳 nil, nil。
 func (r *RedisRepository) GetURL(ctx context.Context, shortCode string) (*model.URL, error) {
+	if r.local != nil {
+		if url, ok := r.local.Get(shortCode); ok {
+			// 回傳副本，避免呼叫端修改到快取內的物件
+			copied := *url
+			return &copied, nil
+		}
+	}
+
 	key := urlCachePrefix + shortCode
 
 	// 用 GETEX：讀取同時刷新 TTL（Redis 6.2+），避免熱門 key 失效造成抖動。
 	data, err := r.client.GetEx(ctx, key, urlCacheTTL).Bytes()
 	if err != nil {
 		if err == redis.Nil {
+			r.redisMisses.Add(1)
 			return nil, nil // Cache miss
 		}
 		return nil, fmt.Errorf("failed to get url from cache: %w", err)
 	}
+	r.redisHits.Add(1)
 
 	var url model.URL
 	if err := json.Unmarshal(data, &url); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal url: %w", err)
 	}
 
+	r.setLocal(&url)
 	return &url, nil
 }
 
+// SetURL 寫入本機 LRU 與 Redis，不廣播失效；變更既有短網址時必須改用 DeleteURL 讓所有副本重新載入。
+// Redis 無法使用時仍會寫入 LRU，讓熱門短網址不必每次都查 PostgreSQL。
 func (r *RedisRepository) SetURL(ctx context.Context, url *model.URL) error {
 	key := urlCachePrefix + url.ShortCode
 
@@ -75,31 +222,181 @@ func (r *RedisRepository) SetURL(ctx context.Context, url *model.URL) error {
 		return fmt.Errorf("failed to marshal url: %w", err)
 	}
 
-	ttl := urlCacheTTL
-	if url.ExpiresAt != nil {
-		remaining := time.Until(*url.ExpiresAt)
-		if remaining < ttl {
-			ttl = remaining
+	copied := *url
+	r.setLocal(&copied)
+
+	if err := r.client.Set(ctx, key, data, urlTTL(url)).Err(); err != nil {
+		return fmt.Errorf("failed to set url in cache: %w", err)
+	}
+	return nil
+}
+
+// SetURLs 以 pipeline 批次寫入 Redis（快取預熱用），回傳實際寫入的筆數。
+// 已過期的短網址略過；不寫入本機 LRU，避免冷門資料擠掉各副本真正的熱門項目。
+func (r *RedisRepository) SetURLs(ctx context.Context, urls []*model.URL) (int, error) {
+	pipe := r.client.Pipeline()
+	queued := 0
+	for _, url := range urls {
+		ttl := urlTTL(url)
+		if ttl <= 0 {
+			continue
+		}
+
+		data, err := json.Marshal(url)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal url: %w", err)
 		}
+		pipe.Set(ctx, urlCachePrefix+url.ShortCode, data, ttl)
+		queued++
+	}
+	if queued == 0 {
+		return 0, nil
 	}
 
-	if err := r.client.Set(ctx, key, data, ttl).Err(); err != nil {
-		return fmt.Errorf("failed to set url in cache: %w", err)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to set urls in cache: %w", err)
 	}
+	return queued, nil
+}
 
-	return nil
+// urlTTL 取 urlCacheTTL 與短網址剩餘有效期較短者；已過期時 <= 0
+func urlTTL(url *model.URL) time.Duration {
+	ttl := urlCacheTTL
+	if url.ExpiresAt != nil {
+		if remaining := time.Until(*url.ExpiresAt); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	return ttl
 }
 
+// DeleteURL 刪除 Redis 與本機快取，並透過 pub/sub 通知其他副本清除各自的 LRU。
+// 失敗時記下短碼，Redis 恢復後自動補刪。
 func (r *RedisRepository) DeleteURL(ctx context.Context, shortCode string) error {
 	key := urlCachePrefix + shortCode
 
+	if r.local != nil {
+		r.local.Delete(shortCode)
+	}
+
 	if err := r.client.Del(ctx, key).Err(); err != nil {
+		r.queueDelete(shortCode)
 		return fmt.Errorf("failed to delete url from cache: %w", err)
 	}
 
+	if r.local != nil {
+		if err := r.client.Publish(ctx, urlInvalidationChannel, r.instanceID+":"+shortCode).Err(); err != nil {
+			r.queueDelete(shortCode)
+			return fmt.Errorf("failed to publish cache invalidation: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// setLocal 寫入 LRU；TTL 取 localTTL 與短網址剩餘有效期較短者，漏收失效訊息時最多過期 localTTL。
+func (r *RedisRepository) setLocal(url *model.URL) {
+	if r.local == nil {
+		return
+	}
+
+	ttl := r.localTTL
+	if url.ExpiresAt != nil {
+		if remaining := time.Until(*url.ExpiresAt); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	r.local.Set(url.ShortCode, url, ttl)
+}
+
+func (r *RedisRepository) listenInvalidations() {
+	defer r.wg.Done()
+
+	ctx := context.Background()
+	for {
+		msg, err := r.pubsub.Receive(ctx)
+		if err != nil {
+			if errors.Is(err, redis.ErrClosed) {
+				return
+			}
+			// go-redis 會在下一次 Receive 時重連並重新訂閱
+			log.Printf("cache invalidation receive failed: err=%v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			// (重新)訂閱成功：斷線期間可能漏掉失效訊息，整個 LRU 清空最保險
+			if m.Kind == "subscribe" {
+				r.local.Purge()
+			}
+		case *redis.Message:
+			instanceID, shortCode, ok := strings.Cut(m.Payload, ":")
+			if ok && instanceID != r.instanceID {
+				r.local.Delete(shortCode)
+			}
+		}
+	}
+}
+
+// AcquireLock 以 SET NX PX 取得名為 name 的鎖；成功時回傳釋放用的 token
+func (r *RedisRepository) AcquireLock(ctx context.Context, name string, ttl time.Duration) (string, bool, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", false, fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	ok, err := r.client.SetNX(ctx, lockPrefix+name, token, ttl).Result()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return token, ok, nil
+}
+
+// ReleaseLock 釋放 AcquireLock 取得的鎖；鎖已過期或被別人持有時不做事
+func (r *RedisRepository) ReleaseLock(ctx context.Context, name, token string) error {
+	if err := releaseLockScript.Run(ctx, r.client, []string{lockPrefix + name}, token).Err(); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// RaiseMaxURLID 把 meta:url_max_id 提高到 id（已較大時不變）
+func (r *RedisRepository) RaiseMaxURLID(ctx context.Context, id int64) error {
+	if err := raiseMaxScript.Run(ctx, r.client, []string{maxURLIDKey}, id).Err(); err != nil {
+		return fmt.Errorf("failed to raise max url id: %w", err)
+	}
+	return nil
+}
+
+// GetMaxURLID 讀取 meta:url_max_id；key 不存在（例如 Redis 被清空）時 ok 為 false
+func (r *RedisRepository) GetMaxURLID(ctx context.Context) (id int64, ok bool, err error) {
+	id, err = r.client.Get(ctx, maxURLIDKey).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to get max url id: %w", err)
+	}
+	return id, true, nil
+}
+
+// CacheStats 回傳兩層快取的命中統計（自行程啟動起累計）
+func (r *RedisRepository) CacheStats() model.CacheStats {
+	stats := model.CacheStats{
+		Redis: model.NewCacheTierStats(r.redisHits.Load(), r.redisMisses.Load()),
+	}
+	if r.local != nil {
+		hits, misses := r.local.Stats()
+		local := model.NewCacheTierStats(hits, misses)
+		local.Size = r.local.Len()
+		stats.Local = &local
+	}
+	return stats
+}
+
 func (r *RedisRepository) IncrementClickCount(ctx context.Context, shortCode string) error {
 	key := clickCountPrefix + shortCode
 
@@ -120,6 +417,67 @@ func (r *RedisRepository) IncrementClickCountBy(ctx context.Context, shortCode s
 	return nil
 }
 
+func (r *RedisRepository) IncrementVariantClickCountBy(ctx context.Context, shortCode, variant string, delta int64) error {
+	return r.incrementCounter(ctx, variantClickKey(shortCode, variant), delta)
+}
+
+// GetVariantClickCounts returns the pending (not yet synced) click count of each variant
+func (r *RedisRepository) GetVariantClickCounts(ctx context.Context, shortCode string, variants []string) (map[string]int64, error) {
+	keys := make([]string, len(variants))
+	for i, variant := range variants {
+		keys[i] = variantClickKey(shortCode, variant)
+	}
+	return r.getCounters(ctx, keys, variants)
+}
+
+func (r *RedisRepository) IncrementSourceClickCountBy(ctx context.Context, shortCode, source string, delta int64) error {
+	return r.incrementCounter(ctx, sourceClickKey(shortCode, source), delta)
+}
+
+// GetSourceClickCounts returns the pending (not yet synced) click count of each traffic source
+func (r *RedisRepository) GetSourceClickCounts(ctx context.Context, shortCode string, sources []string) (map[string]int64, error) {
+	keys := make([]string, len(sources))
+	for i, source := range sources {
+		keys[i] = sourceClickKey(shortCode, source)
+	}
+	return r.getCounters(ctx, keys, sources)
+}
+
+func (r *RedisRepository) incrementCounter(ctx context.Context, key string, delta int64) error {
+	if err := r.client.IncrBy(ctx, key, delta).Err(); err != nil {
+		return fmt.Errorf("failed to increment %s by %d: %w", key, delta, err)
+	}
+
+	return nil
+}
+
+// getCounters MGETs keys and maps each value to the name at the same index; missing keys are omitted
+func (r *RedisRepository) getCounters(ctx context.Context, keys, names []string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(keys))
+	if len(keys) == 0 {
+		return counts, nil
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click counts: %w", err)
+	}
+
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		count, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse click count of %s: %w", keys[i], err)
+		}
+		counts[names[i]] = count
+	}
+
+	return counts, nil
+}
+
 func (r *RedisRepository) GetClickCount(ctx context.Context, shortCode string) (int64, error) {
 	key := clickCountPrefix + shortCode
 
@@ -134,38 +492,109 @@ func (r *RedisRepository) GetClickCount(ctx context.Context, shortCode string) (
 	return count, nil
 }
 
-func (r *RedisRepository) GetAndResetClickCount(ctx context.Context, shortCode string) (int64, error) {
-	key := clickCountPrefix + shortCode
+// DeleteClickCounts 刪除短網址所有未同步的點擊計數（總數、各 variant 與各來源），短網址封存後使用
+func (r *RedisRepository) DeleteClickCounts(ctx context.Context, shortCode string, variants, sources []string) error {
+	keys := []string{clickCountPrefix + shortCode}
+	for _, variant := range variants {
+		keys = append(keys, variantClickKey(shortCode, variant))
+	}
+	for _, source := range sources {
+		keys = append(keys, sourceClickKey(shortCode, source))
+	}
 
-	// 用 GETDEL：同步用「取值+刪除」原子操作，避免同步期間遺漏/重複計數（Redis 6.2+）。
-	count, err := r.client.GetDel(ctx, key).Int64()
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to delete click counts: %w", err)
+	}
+	return nil
+}
+
+// ClickCountKey identifies a clicks: counter; Variant and Source are empty for the link's total counter
+type ClickCountKey struct {
+	ShortCode string
+	Variant   string
+	Source    string
+}
+
+// StageClickCounts SCANs one batch of clicks:* keys from cursor and moves them into the pending hash of runID
+// with a single script call. It returns the number of counters moved and the next cursor (0 once the scan is complete).
+func (r *RedisRepository) StageClickCounts(ctx context.Context, runID string, cursor uint64, count int64) (int, uint64, error) {
+	keys, next, err := r.client.ScanType(ctx, cursor, clickCountPrefix+"*", count, "string").Result()
 	if err != nil {
-		if err == redis.Nil {
-			return 0, nil
-		}
-		return 0, fmt.Errorf("failed to get and reset click count: %w", err)
+		return 0, 0, fmt.Errorf("failed to scan click count keys: %w", err)
+	}
+	if len(keys) == 0 {
+		return 0, next, nil
 	}
 
-	return count, nil
+	result, err := stageCountersScript.Run(ctx, r.client, append([]string{pendingClicksPrefix + runID}, keys...)).Int64Slice()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to stage click counts: %w", err)
+	}
+	if result[1] > 0 {
+		log.Printf("invalid click counters skipped: count=%d", result[1])
+	}
+	return int(result[0]), next, nil
+}
+
+// PendingClickCounts returns the counters staged under runID (empty when the run is already finished)
+func (r *RedisRepository) PendingClickCounts(ctx context.Context, runID string) (map[ClickCountKey]int64, error) {
+	fields, err := r.client.HGetAll(ctx, pendingClicksPrefix+runID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending click counts: %w", err)
+	}
+
+	counts := make(map[ClickCountKey]int64, len(fields))
+	for key, raw := range fields {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pending click count of %s: %w", key, err)
+		}
+		if n != 0 {
+			counts[ParseClickCountKey(key)] += n
+		}
+	}
+	return counts, nil
 }
 
-func (r *RedisRepository) GetAllClickCountKeys(ctx context.Context) ([]string, error) {
-	pattern := clickCountPrefix + "*"
+// DeletePendingClickCounts removes the pending hash of a run once it has been applied
+func (r *RedisRepository) DeletePendingClickCounts(ctx context.Context, runID string) error {
+	if err := r.client.Del(ctx, pendingClicksPrefix+runID).Err(); err != nil {
+		return fmt.Errorf("failed to delete pending click counts: %w", err)
+	}
+	return nil
+}
 
-	var keys []string
-	iter := r.client.Scan(ctx, 0, pattern, 0).Iterator()
+// ListPendingClickRuns returns the run IDs of every pending hash, i.e. runs that were staged but not finished
+func (r *RedisRepository) ListPendingClickRuns(ctx context.Context) ([]string, error) {
+	var runIDs []string
+	iter := r.client.ScanType(ctx, 0, pendingClicksPrefix+"*", 0, "hash").Iterator()
 	for iter.Next(ctx) {
-		keys = append(keys, iter.Val())
+		runIDs = append(runIDs, strings.TrimPrefix(iter.Val(), pendingClicksPrefix))
 	}
 	if err := iter.Err(); err != nil {
-		return nil, fmt.Errorf("failed to scan click count keys: %w", err)
+		return nil, fmt.Errorf("failed to scan pending click runs: %w", err)
 	}
+	return runIDs, nil
+}
+
+// ParseClickCountKey splits a clicks: counter key into its parts
+func ParseClickCountKey(key string) ClickCountKey {
+	rest := strings.TrimPrefix(key, clickCountPrefix)
+	if i := strings.Index(rest, variantClickInfix); i >= 0 {
+		return ClickCountKey{ShortCode: rest[:i], Variant: rest[i+len(variantClickInfix):]}
+	}
+	if i := strings.Index(rest, sourceClickInfix); i >= 0 {
+		return ClickCountKey{ShortCode: rest[:i], Source: rest[i+len(sourceClickInfix):]}
+	}
+	return ClickCountKey{ShortCode: rest}
+}
 
-	return keys, nil
+func variantClickKey(shortCode, variant string) string {
+	return clickCountPrefix + shortCode + variantClickInfix + variant
 }
 
-func ExtractShortCodeFromKey(key string) string {
-	return key[len(clickCountPrefix):]
+func sourceClickKey(shortCode, source string) string {
+	return clickCountPrefix + shortCode + sourceClickInfix + source
 }
 
 func (r *RedisRepository) Health(ctx context.Context) error {
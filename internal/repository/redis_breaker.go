@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/breaker"
+	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrRedisUnavailable 表示 Redis 斷路器開啟中，指令沒有送出（呼叫端應直接走 PostgreSQL 或緩衝）
+var ErrRedisUnavailable = fmt.Errorf("redis unavailable: %w", breaker.ErrOpen)
+
+// maxPendingDeletes 是 Redis 斷線期間記住、待重連後補刪的 url: key 上限
+const maxPendingDeletes = 10000
+
+// breakerHook 讓所有經過 client 的指令（含 pipeline、script）共用同一個斷路器；
+// pub/sub 走獨立連線，不受影響，由 go-redis 自行重連。
+type breakerHook struct {
+	breaker *breaker.Breaker
+}
+
+func (h breakerHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h breakerHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := h.breaker.DoContext(ctx, func() error { return next(ctx, cmd) }, isRedisFailure)
+		if errors.Is(err, breaker.ErrOpen) {
+			cmd.SetErr(ErrRedisUnavailable)
+			return ErrRedisUnavailable
+		}
+		return err
+	}
+}
+
+func (h breakerHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := h.breaker.DoContext(ctx, func() error { return next(ctx, cmds) }, isRedisFailure)
+		if errors.Is(err, breaker.ErrOpen) {
+			for _, cmd := range cmds {
+				cmd.SetErr(ErrRedisUnavailable)
+			}
+			return ErrRedisUnavailable
+		}
+		return err
+	}
+}
+
+// isRedisFailure 只把連線/逾時類錯誤算進斷路器；key 不存在與一般的指令錯誤回覆代表 Redis 正常運作，
+// 但 LOADING、READONLY、MASTERDOWN 表示節點暫時無法服務（重啟載入中、failover 中）。
+// 呼叫端取消（用戶端斷線）不算；呼叫端 ctx 已結束的錯誤由 DoContext 直接略過。
+func isRedisFailure(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var replyErr redis.Error
+	if errors.As(err, &replyErr) {
+		msg := replyErr.Error()
+		return strings.HasPrefix(msg, "LOADING") || strings.HasPrefix(msg, "READONLY") || strings.HasPrefix(msg, "MASTERDOWN")
+	}
+	return true
+}
+
+// Available 回傳 Redis 斷路器是否關閉（Redis 可用）
+func (r *RedisRepository) Available() bool {
+	return r.breaker.State() == breaker.Closed
+}
+
+// BreakerStats 回傳 Redis 斷路器目前的狀態
+func (r *RedisRepository) BreakerStats() model.BreakerStats {
+	return newBreakerStats(r.breaker.Stats())
+}
+
+// OnReconnect 註冊 Redis 恢復（斷路器由開啟轉為關閉）後要執行的 callback，例如把緩衝的點擊寫回 Redis
+func (r *RedisRepository) OnReconnect(fn func()) {
+	r.reconnectMu.Lock()
+	r.reconnectFns = append(r.reconnectFns, fn)
+	r.reconnectMu.Unlock()
+}
+
+func (r *RedisRepository) breakerChanged(from, to breaker.State) {
+	switch to {
+	case breaker.Open:
+		if from == breaker.Closed {
+			log.Printf("Redis unavailable, circuit breaker open: serving from PostgreSQL")
+		}
+	case breaker.Closed:
+		log.Printf("Redis available again, circuit breaker closed")
+
+		r.retryPendingDeletes()
+
+		r.reconnectMu.Lock()
+		fns := append([]func(){}, r.reconnectFns...)
+		r.reconnectMu.Unlock()
+		for _, fn := range fns {
+			fn()
+		}
+	}
+}
+
+// monitor 在斷路器開啟時定期 PING，沒有流量也能自動重新接上 Redis；同時補刪斷線期間沒刪成功的 url: key
+func (r *RedisRepository) monitor(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !r.Available() {
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				_ = r.client.Ping(ctx).Err()
+				cancel()
+				continue
+			}
+			r.retryPendingDeletes()
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// queueDelete 記住刪除失敗的 url: key；不補刪的話，停用的短網址會在 Redis 恢復後繼續被重定向
+func (r *RedisRepository) queueDelete(shortCode string) {
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+
+	if _, ok := r.pendingDeletes[shortCode]; !ok && len(r.pendingDeletes) >= maxPendingDeletes {
+		log.Printf("cache pending deletes full, dropping: shortCode=%s", shortCode)
+		return
+	}
+	r.pendingDeletes[shortCode] = struct{}{}
+}
+
+func (r *RedisRepository) retryPendingDeletes() {
+	r.pendingMu.Lock()
+	if len(r.pendingDeletes) == 0 {
+		r.pendingMu.Unlock()
+		return
+	}
+	codes := make([]string, 0, len(r.pendingDeletes))
+	for code := range r.pendingDeletes {
+		codes = append(codes, code)
+	}
+	r.pendingMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deleted := 0
+	for _, code := range codes {
+		if err := r.DeleteURL(ctx, code); err != nil {
+			log.Printf("cache pending delete failed: remaining=%d err=%v", len(codes)-deleted, err)
+			return
+		}
+
+		r.pendingMu.Lock()
+		delete(r.pendingDeletes, code)
+		r.pendingMu.Unlock()
+		deleted++
+	}
+	log.Printf("Cache pending deletes applied: count=%d", deleted)
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// leasePrefix 是 leader lease 的 key：lock:leader:<name>，值為 <fence>:<holder>
+	leasePrefix = lockPrefix + "leader:"
+	// fencePrefix 是 fencing token 計數器：meta:fence:<name>，每次有新的 leader 取得 lease 就加一
+	fencePrefix = "meta:fence:"
+)
+
+// acquireLeaseScript 在 lease 不存在時取得新的 fencing token 並寫入 lease；已被持有時回傳 0
+var acquireLeaseScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+local fence = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], fence .. ':' .. ARGV[1], 'PX', ARGV[2])
+return fence
+`)
+
+// renewLeaseScript 只在 lease 仍是自己的（值相符）時延長
+var renewLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// refenceLeaseScript 讓目前的 lease 持有者換一個大於 ARGV[2] 的 fencing token（Redis 資料遺失、計數器歸零時使用）
+var refenceLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+local fence = redis.call('INCR', KEYS[2])
+if fence <= tonumber(ARGV[2]) then
+	fence = tonumber(ARGV[2]) + 1
+	redis.call('SET', KEYS[2], fence)
+end
+redis.call('SET', KEYS[1], fence .. ':' .. ARGV[3], 'PX', ARGV[4])
+return fence
+`)
+
+// Lease is the current holder of a leader lease
+type Lease struct {
+	Holder string
+	Fence  int64
+	// TTL is the time left before the lease expires unless renewed
+	TTL time.Duration
+}
+
+func leaseValue(holder string, fence int64) string {
+	return strconv.FormatInt(fence, 10) + ":" + holder
+}
+
+// AcquireLease 嘗試取得名為 name 的 leader lease；成功時回傳這次的 fencing token（單調遞增）
+func (r *RedisRepository) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (int64, bool, error) {
+	fence, err := acquireLeaseScript.Run(ctx, r.client, []string{leasePrefix + name, fencePrefix + name}, holder, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	return fence, fence > 0, nil
+}
+
+// RenewLease 延長自己持有的 lease；lease 已過期或已被別人取得時回傳 false
+func (r *RedisRepository) RenewLease(ctx context.Context, name, holder string, fence int64, ttl time.Duration) (bool, error) {
+	renewed, err := renewLeaseScript.Run(ctx, r.client, []string{leasePrefix + name}, leaseValue(holder, fence), ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	return renewed == 1, nil
+}
+
+// RefenceLease 在仍持有 lease 時換發一個大於 floor 的 fencing token；lease 已不是自己的時回傳 false
+func (r *RedisRepository) RefenceLease(ctx context.Context, name, holder string, fence, floor int64, ttl time.Duration) (int64, bool, error) {
+	newFence, err := refenceLeaseScript.Run(ctx, r.client, []string{leasePrefix + name, fencePrefix + name},
+		leaseValue(holder, fence), floor, holder, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to refence lease: %w", err)
+	}
+	return newFence, newFence > 0, nil
+}
+
+// ReleaseLease 交出自己持有的 lease，讓其他副本不必等到過期就能接手
+func (r *RedisRepository) ReleaseLease(ctx context.Context, name, holder string, fence int64) error {
+	if err := releaseLockScript.Run(ctx, r.client, []string{leasePrefix + name}, leaseValue(holder, fence)).Err(); err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
+
+// GetLease 回傳目前的 lease 持有者；沒有 leader 時回傳 nil
+func (r *RedisRepository) GetLease(ctx context.Context, name string) (*Lease, error) {
+	pipe := r.client.Pipeline()
+	valueCmd := pipe.Get(ctx, leasePrefix+name)
+	ttlCmd := pipe.PTTL(ctx, leasePrefix+name)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get lease: %w", err)
+	}
+
+	value, err := valueCmd.Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lease: %w", err)
+	}
+
+	raw, holder, ok := strings.Cut(value, ":")
+	fence, err := strconv.ParseInt(raw, 10, 64)
+	if !ok || err != nil {
+		return nil, fmt.Errorf("invalid lease value %q", value)
+	}
+	return &Lease{Holder: holder, Fence: fence, TTL: ttlCmd.Val()}, nil
+}
@@ -2,130 +2,252 @@ package scheduler
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log"
-	"sync"
 	"time"
 
+	"github.com/jack/golang-short-url-service/internal/leader"
 	"github.com/jack/golang-short-url-service/internal/repository"
 )
 
-// ClickSyncScheduler handles periodic synchronization of click counts from Redis to PostgreSQL
-type ClickSyncScheduler struct {
+// clickSyncChunkTimeout bounds one stage + apply round trip; a full sync is bounded by the job timeout
+const clickSyncChunkTimeout = 30 * time.Second
+
+// clickSyncRunRetention is how long applied run IDs are kept in click_sync_runs to detect replays
+const clickSyncRunRetention = 7 * 24 * time.Hour
+
+// ClickSync moves click counts from Redis to PostgreSQL; its Run method is the click-sync job
+type ClickSync struct {
 	postgresRepo *repository.PostgresRepository
 	redisRepo    *repository.RedisRepository
-	interval     time.Duration
-	stopCh       chan struct{}
-	wg           sync.WaitGroup
+	batchSize    int
+	// elector 決定由哪個副本同步（nil 表示不做選舉，一律同步）
+	elector *leader.Elector
 }
 
-// NewClickSyncScheduler creates a new click sync scheduler
-func NewClickSyncScheduler(
+// NewClickSync creates the click sync job
+func NewClickSync(
 	postgresRepo *repository.PostgresRepository,
 	redisRepo *repository.RedisRepository,
-	interval time.Duration,
-) *ClickSyncScheduler {
-	return &ClickSyncScheduler{
+	elector *leader.Elector,
+	batchSize int,
+) *ClickSync {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	return &ClickSync{
 		postgresRepo: postgresRepo,
 		redisRepo:    redisRepo,
-		interval:     interval,
-		stopCh:       make(chan struct{}),
+		batchSize:    batchSize,
+		elector:      elector,
 	}
 }
 
-// Start begins the periodic sync process
-func (s *ClickSyncScheduler) Start() {
-	s.wg.Add(1)
-	go s.run()
-	log.Printf("Click sync scheduler started (interval: %v)", s.interval)
+// SyncStats summarizes one click sync run
+type SyncStats struct {
+	Chunks int
+	Keys   int
+	Clicks int64
+	// Replayed is the number of pending runs left by an earlier sync that were finished
+	Replayed int
+	// Duplicates is the number of pending runs that had already been applied (only the Redis cleanup was left)
+	Duplicates int
+	Elapsed    time.Duration
 }
 
-// Stop gracefully stops the scheduler
-func (s *ClickSyncScheduler) Stop() {
-	close(s.stopCh)
-	s.wg.Wait()
-	log.Println("Click sync scheduler stopped")
+// KeysPerSecond is the sync throughput
+func (st SyncStats) KeysPerSecond() float64 {
+	if st.Elapsed <= 0 {
+		return 0
+	}
+	return float64(st.Keys) / st.Elapsed.Seconds()
 }
 
-func (s *ClickSyncScheduler) run() {
-	defer s.wg.Done()
+// Run syncs all pending click counts from Redis to PostgreSQL and prunes old ledger rows
+func (s *ClickSync) Run(ctx context.Context) error {
+	stats, err := s.Sync(ctx)
+	if stats.Keys > 0 || stats.Replayed > 0 || stats.Duplicates > 0 {
+		log.Printf("Click count sync completed: keys=%d clicks=%d chunks=%d replayed=%d duplicates=%d elapsed=%s (%.0f keys/s)",
+			stats.Keys, stats.Clicks, stats.Chunks, stats.Replayed, stats.Duplicates, stats.Elapsed.Round(time.Millisecond), stats.KeysPerSecond())
+	}
+	if err != nil {
+		return fmt.Errorf("click count sync aborted: %w", err)
+	}
+
+	s.pruneRuns(ctx)
+	return nil
+}
 
-	ticker := time.NewTicker(s.interval)
-	defer ticker.Stop()
+// Sync moves click counters from Redis to PostgreSQL with a two-phase protocol, one run per chunk of batchSize keys:
+//
+//  1. SCAN a chunk of clicks:* keys and move them atomically (one Lua script) into the hash clicks:pending:<runID>
+//  2. apply the hash in one PostgreSQL transaction that also inserts runID into click_sync_runs
+//  3. delete the hash
+//
+// A crash or error after step 1 leaves the hash behind; it is replayed by the next sync (and on startup),
+// and the click_sync_runs row makes a replay of an already applied run a no-op.
+func (s *ClickSync) Sync(ctx context.Context) (stats SyncStats, err error) {
+	start := time.Now()
+	defer func() { stats.Elapsed = time.Since(start) }()
+
+	if _, leading := s.fence(); !leading {
+		return stats, ErrNotLeader
+	}
+	if err := s.replayPending(ctx, &stats); err != nil {
+		return stats, err
+	}
 
+	var cursor uint64
 	for {
-		select {
-		case <-ticker.C:
-			s.syncClickCounts()
-		case <-s.stopCh:
-			// Perform final sync before stopping
-			log.Println("Performing final click count sync before shutdown...")
-			s.syncClickCounts()
-			return
+		next, err := s.syncChunk(ctx, cursor, &stats)
+		if err != nil {
+			return stats, err
 		}
+		if next == 0 {
+			return stats, nil
+		}
+		cursor = next
 	}
 }
 
-// syncClickCounts syncs all pending click counts from Redis to PostgreSQL
-func (s *ClickSyncScheduler) syncClickCounts() {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+func (s *ClickSync) syncChunk(ctx context.Context, cursor uint64, stats *SyncStats) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, clickSyncChunkTimeout)
 	defer cancel()
 
-	// Get all click count keys
-	keys, err := s.redisRepo.GetAllClickCountKeys(ctx)
-	if err != nil {
-		log.Printf("Failed to get click count keys: %v", err)
-		return
+	// 每批開始前確認仍是 leader；寫入時 PostgreSQL 會再以 fencing token 檢查一次
+	if _, leading := s.fence(); !leading {
+		return 0, ErrNotLeader
 	}
 
-	if len(keys) == 0 {
-		return
+	runID, err := newRunID()
+	if err != nil {
+		return 0, err
 	}
 
-	log.Printf("Syncing click counts for %d URLs...", len(keys))
+	staged, next, err := s.redisRepo.StageClickCounts(ctx, runID, cursor, int64(s.batchSize))
+	if err != nil {
+		return 0, err
+	}
+	if staged == 0 {
+		return next, nil
+	}
 
-	var successCount, failCount int
+	if _, err := s.finishRun(ctx, runID, stats); err != nil {
+		// 計數留在 clicks:pending:<runID>，下次同步重播
+		return 0, fmt.Errorf("run %s left pending: %w", runID, err)
+	}
+	stats.Chunks++
+	return next, nil
+}
 
-	for _, key := range keys {
-		shortCode := repository.ExtractShortCodeFromKey(key)
+// replayPending finishes runs staged by an earlier sync (this or another replica) that never got deleted
+func (s *ClickSync) replayPending(ctx context.Context, stats *SyncStats) error {
+	runIDs, err := s.redisRepo.ListPendingClickRuns(ctx)
+	if err != nil {
+		return err
+	}
 
-		// Atomically get and reset the count
-		count, err := s.redisRepo.GetAndResetClickCount(ctx, shortCode)
+	for _, runID := range runIDs {
+		runCtx, cancel := context.WithTimeout(ctx, clickSyncChunkTimeout)
+		applied, err := s.finishRun(runCtx, runID, stats)
+		cancel()
 		if err != nil {
-			log.Printf("Failed to get click count for %s: %v", shortCode, err)
-			failCount++
-			continue
+			return fmt.Errorf("replay of run %s failed: %w", runID, err)
 		}
-
-		if count == 0 {
-			continue
+		if applied {
+			stats.Replayed++
+		} else {
+			stats.Duplicates++
 		}
+	}
+	return nil
+}
+
+// finishRun applies the pending hash of runID (unless the ledger says it is already applied) and deletes it
+func (s *ClickSync) finishRun(ctx context.Context, runID string, stats *SyncStats) (bool, error) {
+	fence, leading := s.fence()
+	if !leading {
+		return false, ErrNotLeader
+	}
+
+	counts, err := s.redisRepo.PendingClickCounts(ctx, runID)
+	if err != nil {
+		return false, err
+	}
 
-		// Update database with the accumulated count
-		if err := s.postgresRepo.IncrementClickCountBy(ctx, shortCode, count); err != nil {
-			// On failure, try to restore the count to Redis
-			log.Printf("Failed to sync click count for %s: %v", shortCode, err)
-			if restoreErr := s.restoreClickCount(ctx, shortCode, count); restoreErr != nil {
-				log.Printf("Failed to restore click count for %s: %v (data loss: %d clicks)", shortCode, restoreErr, count)
+	applied := false
+	if len(counts) > 0 {
+		applied, err = s.postgresRepo.ApplyClickCounts(ctx, runID, fence, counts)
+		if errors.Is(err, repository.ErrFenced) {
+			fence, err = s.refence(ctx)
+			if err == nil {
+				applied, err = s.postgresRepo.ApplyClickCounts(ctx, runID, fence, counts)
 			}
-			failCount++
-			continue
 		}
+		if err != nil {
+			return false, err
+		}
+	}
+	if applied {
+		stats.Keys += len(counts)
+		stats.Clicks += sumCounts(counts)
+	}
 
-		successCount++
+	// 刪除失敗也不影響正確性：下次重播時 click_sync_runs 已有紀錄，只會再刪一次
+	if err := s.redisRepo.DeletePendingClickCounts(ctx, runID); err != nil {
+		log.Printf("Failed to delete pending click counts: run=%s err=%v", runID, err)
 	}
+	return applied, nil
+}
 
-	if successCount > 0 || failCount > 0 {
-		log.Printf("Click count sync completed: %d success, %d failed", successCount, failCount)
+// pruneRuns drops ledger rows older than clickSyncRunRetention; pending runs are replayed long before that
+func (s *ClickSync) pruneRuns(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, clickSyncChunkTimeout)
+	defer cancel()
+
+	if _, err := s.postgresRepo.PruneClickSyncRuns(ctx, time.Now().Add(-clickSyncRunRetention)); err != nil {
+		log.Printf("Failed to prune click sync runs: %v", err)
 	}
 }
 
-// restoreClickCount restores click count to Redis if database sync fails
-func (s *ClickSyncScheduler) restoreClickCount(ctx context.Context, shortCode string, count int64) error {
-	return s.redisRepo.IncrementClickCountBy(ctx, shortCode, count)
+// fence returns the leader fencing token (0 without an elector) and whether this replica may sync
+func (s *ClickSync) fence() (int64, bool) {
+	if s.elector == nil {
+		return 0, true
+	}
+	return s.elector.Leading()
 }
 
-// SyncNow triggers an immediate sync (useful for graceful shutdown)
-func (s *ClickSyncScheduler) SyncNow() {
-	s.syncClickCounts()
+// refence handles a write rejected for a stale fencing token. If this replica still holds the lease in Redis,
+// no newer leader exists and the token counter was lost with Redis data, so a token above PostgreSQL's is issued.
+func (s *ClickSync) refence(ctx context.Context) (int64, error) {
+	floor, err := s.postgresRepo.ClickSyncFence(ctx)
+	if err != nil {
+		return 0, err
+	}
+	fence, err := s.elector.Refence(ctx, floor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", repository.ErrFenced, err)
+	}
+	return fence, nil
 }
 
+// newRunID returns a sortable, unique run ID: <UTC timestamp>-<random hex>
+func newRunID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate run id: %w", err)
+	}
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(buf), nil
+}
+
+func sumCounts(counts map[repository.ClickCountKey]int64) int64 {
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		from string
+		want string
+	}{
+		{"every 15 minutes", "*/15 * * * *", "2026-03-01T10:07:00Z", "2026-03-01T10:15:00Z"},
+		{"same minute is not repeated", "*/15 * * * *", "2026-03-01T10:15:00Z", "2026-03-01T10:30:00Z"},
+		{"list", "5,35 * * * *", "2026-03-01T10:35:30Z", "2026-03-01T11:05:00Z"},
+		{"hour step", "0 */6 * * *", "2026-03-01T05:59:59Z", "2026-03-01T06:00:00Z"},
+		{"range with step", "30 9-17/4 * * *", "2026-03-01T14:00:00Z", "2026-03-01T17:30:00Z"},
+		{"value with step", "0 20/2 * * *", "2026-03-01T21:00:00Z", "2026-03-01T22:00:00Z"},
+		{"daily rolls over to the next day", "0 3 * * *", "2026-03-01T03:00:00Z", "2026-03-02T03:00:00Z"},
+		{"evaluated in UTC", "0 3 * * *", "2026-03-01T10:00:00+08:00", "2026-03-01T03:00:00Z"},
+		{"weekdays", "0 9 * * 1-5", "2026-03-06T10:00:00Z", "2026-03-09T09:00:00Z"},
+		{"sunday as 7", "0 0 * * 7", "2026-03-02T00:00:00Z", "2026-03-08T00:00:00Z"},
+		{"day of month list", "0 0 1,15 * *", "2026-01-02T00:00:00Z", "2026-01-15T00:00:00Z"},
+		{"day of month or day of week", "0 0 13 * 5", "2026-03-01T00:00:00Z", "2026-03-06T00:00:00Z"},
+		{"both day fields match", "0 0 13 * 5", "2026-03-07T00:00:00Z", "2026-03-13T00:00:00Z"},
+		{"stepped day of month is unrestricted", "0 0 */2 * 1", "2026-03-01T00:00:00Z", "2026-03-02T00:00:00Z"},
+		{"stepped day of week is unrestricted", "0 0 13 * */1", "2026-03-01T00:00:00Z", "2026-03-13T00:00:00Z"},
+		{"skips months without the day", "0 0 31 * *", "2026-04-01T00:00:00Z", "2026-05-31T00:00:00Z"},
+		{"month rollover", "0 0 1 * *", "2026-01-31T12:00:00Z", "2026-02-01T00:00:00Z"},
+		{"year rollover", "59 23 31 12 *", "2026-12-31T23:59:00Z", "2027-12-31T23:59:00Z"},
+		{"leap day", "0 12 29 2 *", "2026-03-01T00:00:00Z", "2028-02-29T12:00:00Z"},
+		{"month range", "0 0 1 6-8 *", "2026-08-02T00:00:00Z", "2027-06-01T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q): %v", tt.spec, err)
+			}
+			from, _ := time.Parse(time.RFC3339, tt.from)
+			want, _ := time.Parse(time.RFC3339, tt.want)
+			if got := schedule.Next(from); !got.Equal(want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got.Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+		want    string
+	}{
+		{"", false, ""},
+		{"off", false, ""},
+		{"15m", false, "every 15m0s"},
+		{"0 3 * * *", false, "cron 0 3 * * *"},
+		{"-1m", true, ""},
+		{"* * * *", true, ""},
+		{"60 * * * *", true, ""},
+		{"* 24 * * *", true, ""},
+		{"* * 0 * *", true, ""},
+		{"* * * 13 *", true, ""},
+		{"* * * * 8", true, ""},
+		{"5-1 * * * *", true, ""},
+		{"*/0 * * * *", true, ""},
+		{"a * * * *", true, ""},
+		{"0 0 30 2 *", true, ""},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSchedule(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		got := ""
+		if schedule != nil {
+			got = schedule.String()
+		}
+		if got != tt.want {
+			t.Errorf("ParseSchedule(%q) = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+// Package scheduler runs the service's periodic background jobs (click sync, purges, rollups).
+//
+// 每個 job 有自己的排程（固定間隔或 cron）、jitter 與單次執行逾時；同一個 job 同時只會有一次執行，
+// 排程到點時上一次還沒結束就略過。Leader 限定的 job 只在持有 leader lease 的副本執行。
+// 各 job 的執行狀態（上次開始/結束、耗時、錯誤）可由管理 API 查詢，也可以手動觸發。
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/leader"
+	"github.com/jack/golang-short-url-service/internal/model"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+	// ErrNotLeader 表示 leader 限定的 job 在非 leader（或已失去 leadership）的副本上執行
+	ErrNotLeader = errors.New("not the leader")
+)
+
+// Job describes a background job
+type Job struct {
+	Name string
+	// Schedule 為 nil 時不排程，只能手動觸發
+	Schedule Schedule
+	// Jitter 在每次排程時間上加上 [0, Jitter) 的隨機延遲，避免多個 job 或副本同時啟動
+	Jitter time.Duration
+	// Timeout 是單次執行的上限（0 表示不限制）
+	Timeout time.Duration
+	// Leader 不為 nil 時只有 leader 副本執行（排程與手動觸發皆同）
+	Leader *leader.Elector
+	// RunOnStop 在 Scheduler.Stop 時再執行一次，例如關閉前的最後一次點擊同步
+	RunOnStop bool
+	Run       func(ctx context.Context) error
+}
+
+type jobState struct {
+	job Job
+
+	mu       sync.Mutex
+	running  bool
+	next     time.Time
+	last     *model.JobRun
+	runs     int64
+	failures int64
+	skipped  int64
+}
+
+// Scheduler runs registered jobs on their schedules
+type Scheduler struct {
+	jobs   []*jobState
+	byName map[string]*jobState
+
+	stopCh chan struct{}
+	// ctx 是排程與手動觸發的執行的上層 context，Stop 時取消，讓執行中的 job 盡快結束
+	ctx    context.Context
+	cancel context.CancelFunc
+	loops  sync.WaitGroup
+	runs   sync.WaitGroup
+}
+
+func New() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		byName: make(map[string]*jobState),
+		stopCh: make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Register 加入一個 job；必須在 Start 之前呼叫
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("job needs a name and a run function")
+	}
+	if _, ok := s.byName[job.Name]; ok {
+		return fmt.Errorf("job %q already registered", job.Name)
+	}
+
+	st := &jobState{job: job}
+	s.jobs = append(s.jobs, st)
+	s.byName[job.Name] = st
+	return nil
+}
+
+// Start 為每個有排程的 job 啟動計時 goroutine
+func (s *Scheduler) Start() {
+	for _, st := range s.jobs {
+		if st.job.Schedule == nil {
+			log.Printf("Job registered: name=%s schedule=manual", st.job.Name)
+			continue
+		}
+
+		s.loops.Add(1)
+		go s.loop(st)
+		log.Printf("Job scheduled: name=%s schedule=%s jitter=%v timeout=%v", st.job.Name, st.job.Schedule, st.job.Jitter, st.job.Timeout)
+	}
+}
+
+// Stop 停止排程、取消並等待執行中的 job 結束，再執行設定了 RunOnStop 的 job
+func (s *Scheduler) Stop() {
+	close(s.stopCh)
+	s.cancel()
+	s.loops.Wait()
+	s.runs.Wait()
+
+	for _, st := range s.jobs {
+		if !st.job.RunOnStop {
+			continue
+		}
+		if err := s.tryStart(st, model.JobTriggerShutdown); err != nil {
+			continue
+		}
+		log.Printf("Running job before shutdown: name=%s", st.job.Name)
+		// 上層 context 已取消，關閉前的最後一次執行只受 job 自己的逾時限制
+		s.execute(context.Background(), st)
+	}
+	log.Println("Scheduler stopped")
+}
+
+// Trigger 立即在背景執行一次 job；執行中回傳 ErrJobRunning，leader 限定的 job 在非 leader 副本回傳 ErrNotLeader
+func (s *Scheduler) Trigger(name string) (model.JobStatus, error) {
+	st, ok := s.byName[name]
+	if !ok {
+		return model.JobStatus{}, ErrJobNotFound
+	}
+	if err := s.tryStart(st, model.JobTriggerManual); err != nil {
+		return st.status(), err
+	}
+
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		s.execute(s.ctx, st)
+	}()
+
+	log.Printf("Job triggered manually: name=%s", name)
+	return st.status(), nil
+}
+
+// Jobs 回傳所有 job 的狀態（依註冊順序）
+func (s *Scheduler) Jobs() []model.JobStatus {
+	statuses := make([]model.JobStatus, 0, len(s.jobs))
+	for _, st := range s.jobs {
+		statuses = append(statuses, st.status())
+	}
+	return statuses
+}
+
+func (s *Scheduler) loop(st *jobState) {
+	defer s.loops.Done()
+
+	for {
+		next := st.job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		if st.job.Jitter > 0 {
+			next = next.Add(rand.N(st.job.Jitter))
+		}
+		st.setNext(next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			if err := s.tryStart(st, model.JobTriggerSchedule); err != nil {
+				if errors.Is(err, ErrJobRunning) {
+					st.skip()
+					log.Printf("Job skipped: name=%s (previous run still in progress)", st.job.Name)
+				}
+				continue
+			}
+			s.execute(s.ctx, st)
+		case <-s.stopCh:
+			timer.Stop()
+			st.setNext(time.Time{})
+			return
+		}
+	}
+}
+
+// tryStart 檢查 leader 並佔用 job（防止重疊執行）；成功後必須呼叫 execute
+func (s *Scheduler) tryStart(st *jobState, trigger string) error {
+	if st.job.Leader != nil {
+		if _, leading := st.job.Leader.Leading(); !leading {
+			return ErrNotLeader
+		}
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.running {
+		return ErrJobRunning
+	}
+	st.running = true
+	st.last = &model.JobRun{Trigger: trigger, Status: model.JobRunRunning, StartedAt: time.Now()}
+	return nil
+}
+
+func (s *Scheduler) execute(ctx context.Context, st *jobState) {
+	st.mu.Lock()
+	trigger, start := st.last.Trigger, st.last.StartedAt
+	st.mu.Unlock()
+
+	if st.job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, st.job.Timeout)
+		defer cancel()
+	}
+
+	err := runJob(ctx, st.job.Run)
+	elapsed := time.Since(start)
+	if err != nil {
+		log.Printf("Job failed: name=%s trigger=%s elapsed=%s err=%v", st.job.Name, trigger, elapsed.Round(time.Millisecond), err)
+	}
+
+	st.finish(elapsed, err)
+}
+
+// runJob 執行 job 並把 panic 轉成錯誤，避免單一 job 拖垮整個行程
+func runJob(ctx context.Context, run func(ctx context.Context) error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return run(ctx)
+}
+
+func (st *jobState) finish(elapsed time.Duration, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	finishedAt := st.last.StartedAt.Add(elapsed)
+	st.last.FinishedAt = &finishedAt
+	st.last.DurationMS = float64(elapsed.Microseconds()) / 1000
+	st.last.Status = model.JobRunSucceeded
+	st.runs++
+	if err != nil {
+		st.last.Status = model.JobRunFailed
+		st.last.Error = err.Error()
+		st.failures++
+	}
+	st.running = false
+}
+
+func (st *jobState) setNext(next time.Time) {
+	st.mu.Lock()
+	st.next = next
+	st.mu.Unlock()
+}
+
+func (st *jobState) skip() {
+	st.mu.Lock()
+	st.skipped++
+	st.mu.Unlock()
+}
+
+func (st *jobState) status() model.JobStatus {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	status := model.JobStatus{
+		Name:       st.job.Name,
+		Schedule:   "manual",
+		LeaderOnly: st.job.Leader != nil,
+		Running:    st.running,
+		Runs:       st.runs,
+		Failures:   st.failures,
+		Skipped:    st.skipped,
+	}
+	if st.job.Schedule != nil {
+		status.Schedule = st.job.Schedule.String()
+	}
+	if st.job.Jitter > 0 {
+		status.Jitter = st.job.Jitter.String()
+	}
+	if st.job.Timeout > 0 {
+		status.Timeout = st.job.Timeout.String()
+	}
+	if !st.next.IsZero() {
+		next := st.next
+		status.NextRunAt = &next
+	}
+	if st.last != nil {
+		last := *st.last
+		if st.running {
+			last.DurationMS = float64(time.Since(last.StartedAt).Microseconds()) / 1000
+		}
+		status.LastRun = &last
+	}
+	return status
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/jack/golang-short-url-service/internal/model"
+)
+
+// ReportAbuse 記錄使用者檢舉；同一 IP 對同一短網址已有未處理檢舉時不重複建立。
+// 已停用的短網址不再收檢舉（已處理完畢，不需要再進審核佇列）。
+func (s *ShortURLService) ReportAbuse(ctx context.Context, shortCode string, req *model.CreateReportRequest, reporterIP, userAgent string) error {
+	url, err := s.postgresRepo.GetURLByShortCode(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if !url.IsActive {
+		return nil
+	}
+
+	report := &model.AbuseReport{
+		URLID:         url.ID,
+		Reason:        req.Reason,
+		Details:       req.Details,
+		ReporterEmail: req.Email,
+		ReporterIP:    reporterIP,
+		UserAgent:     userAgent,
+	}
+	created, err := s.postgresRepo.CreateAbuseReport(ctx, report)
+	if err != nil {
+		return err
+	}
+	if created {
+		log.Printf("abuse report received: id=%d shortCode=%s reason=%s", report.ID, shortCode, report.Reason)
+	}
+
+	return nil
+}
+
+func (s *ShortURLService) ListAbuseReports(ctx context.Context, status string, afterID int64, limit int) (*model.ListReportsResponse, error) {
+	reports, err := s.postgresRepo.ListAbuseReports(ctx, status, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.ListReportsResponse{Reports: reports}
+	if len(reports) == limit {
+		response.NextAfter = reports[len(reports)-1].ID
+	}
+	return response, nil
+}
+
+func (s *ShortURLService) GetAbuseReport(ctx context.Context, id int64) (*model.AbuseReport, error) {
+	return s.postgresRepo.GetAbuseReport(ctx, id)
+}
+
+// DisableReportedURL 處理檢舉：停用短網址（is_active=false）、結案所有相關檢舉並清除 url: 快取。
+func (s *ShortURLService) DisableReportedURL(ctx context.Context, id int64, resolvedBy, note string) (*model.AbuseReport, error) {
+	report, err := s.postgresRepo.ResolveAbuseReport(ctx, id, model.ReportStatusActioned, resolvedBy, note)
+	if err != nil {
+		return nil, err
+	}
+
+	// 快取刪除失敗時，最長要等 urlCacheTTL 到期才會停止重定向，必須留下 log
+	if err := s.redisRepo.DeleteURL(ctx, report.ShortCode); err != nil {
+		log.Printf("cache delete url failed: shortCode=%s err=%v", report.ShortCode, err)
+	}
+	log.Printf("abuse report actioned: id=%d shortCode=%s by=%s", report.ID, report.ShortCode, resolvedBy)
+
+	return report, nil
+}
+
+// DismissAbuseReport 駁回檢舉，短網址維持原狀。
+func (s *ShortURLService) DismissAbuseReport(ctx context.Context, id int64, resolvedBy, note string) (*model.AbuseReport, error) {
+	report, err := s.postgresRepo.ResolveAbuseReport(ctx, id, model.ReportStatusDismissed, resolvedBy, note)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("abuse report dismissed: id=%d shortCode=%s by=%s", report.ID, report.ShortCode, resolvedBy)
+	return report, nil
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	DedupModeExact     = "exact"
+	DedupModeCanonical = "canonical"
+	DedupModeOff       = "off"
+)
+
+// ErrInvalidDestination 目的地無法標準化（例如不合法的 IDN 網域）
+var ErrInvalidDestination = errors.New("invalid destination url")
+
+// trackingParams 是 URL_CANON_STRIP_TRACKING 開啟時移除的追蹤參數（utm_* 另外以前綴判斷）
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"gbraid":  true,
+	"wbraid":  true,
+	"msclkid": true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"igshid":  true,
+	"yclid":   true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// hostProfile 把非 ASCII 的 IDN 轉成 punycode；不套用 STD3 規則，與 ASCII 主機名一樣接受底線
+var hostProfile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false))
+
+type canonicalOptions struct {
+	sortQuery     bool
+	stripTracking bool
+}
+
+// canonicalizeURL 產生去重用的標準形式：scheme/host 小寫、IDN 轉 punycode、移除預設 port、
+// 空 path 補 "/"、percent-encoding 正規化（unreserved 字元解碼、其餘十六進位轉大寫）、移除空的 "?"，
+// 並可選擇排序 query 參數與移除追蹤參數。fragment 保留（SPA 常以 fragment 區分頁面）。
+func canonicalizeURL(raw string, opts canonicalOptions) (string, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+		if ip.To4() == nil {
+			host = "[" + host + "]"
+		}
+	} else if !isASCII(host) {
+		host, err = hostProfile.ToASCII(host)
+		if err != nil {
+			return "", fmt.Errorf("invalid host %q: %w", parsed.Hostname(), err)
+		}
+	}
+	if port := parsed.Port(); port != "" && port != defaultPorts[scheme] {
+		host += ":" + port
+	}
+
+	path := normalizePercentEncoding(parsed.EscapedPath())
+	if path == "" {
+		path = "/"
+	}
+
+	var b strings.Builder
+	b.WriteString(scheme)
+	b.WriteString("://")
+	if parsed.User != nil {
+		b.WriteString(parsed.User.String())
+		b.WriteByte('@')
+	}
+	b.WriteString(host)
+	b.WriteString(path)
+
+	if query := canonicalQuery(parsed.RawQuery, opts); query != "" {
+		b.WriteByte('?')
+		b.WriteString(query)
+	}
+	if parsed.Fragment != "" {
+		b.WriteByte('#')
+		b.WriteString(normalizePercentEncoding(parsed.EscapedFragment()))
+	}
+
+	return b.String(), nil
+}
+
+func canonicalQuery(rawQuery string, opts canonicalOptions) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	type param struct {
+		key string
+		raw string
+	}
+
+	var params []param
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		part = normalizePercentEncoding(part)
+		name, _, _ := strings.Cut(part, "=")
+		key, err := url.QueryUnescape(name)
+		if err != nil {
+			key = name
+		}
+		if opts.stripTracking && isTrackingParam(key) {
+			continue
+		}
+		params = append(params, param{key: key, raw: part})
+	}
+
+	if opts.sortQuery {
+		// 同名參數的相對順序有意義（例如 a=1&a=2），使用 stable sort 只依 key 排序
+		sort.SliceStable(params, func(i, j int) bool { return params[i].key < params[j].key })
+	}
+
+	parts := make([]string, len(params))
+	for i, p := range params {
+		parts[i] = p.raw
+	}
+	return strings.Join(parts, "&")
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "utm_") || trackingParams[key]
+}
+
+// normalizePercentEncoding 解碼被多餘編碼的 unreserved 字元（%41 → A、%7E → ~），其餘 escape 轉成大寫十六進位。
+func normalizePercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			c := unhex(s[i+1])<<4 | unhex(s[i+2])
+			if isUnreserved(c) {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('%')
+				b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+			}
+			i += 2
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// isASCII 回傳 s 是否只含 ASCII；ASCII 主機名只需小寫化，不經過 IDNA 驗證
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
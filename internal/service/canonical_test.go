@@ -0,0 +1,27 @@
+package service
+
+import "testing"
+
+func TestCanonicalizeURLHosts(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"https://my_bucket.s3.amazonaws.com/file.txt", "https://my_bucket.s3.amazonaws.com/file.txt"},
+		{"https://-edge-.example.com", "https://-edge-.example.com/"},
+		{"HTTPS://Example.COM:443", "https://example.com/"},
+		{"https://Bücher.example/", "https://xn--bcher-kva.example/"},
+		{"http://[::1]:8080/x", "http://[::1]:8080/x"},
+	}
+
+	for _, tt := range tests {
+		got, err := canonicalizeURL(tt.raw, canonicalOptions{})
+		if err != nil {
+			t.Errorf("canonicalizeURL(%q) error: %v", tt.raw, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("canonicalizeURL(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/repository"
+)
+
+// clickWriteTimeout 是緩衝區已滿、直接寫入 PostgreSQL 時單次寫入的上限
+const clickWriteTimeout = time.Second
+
+// clickCounter 是點擊計數可以寫入的地方：Redis（平常）或 PostgreSQL（Redis 無法使用時）
+type clickCounter interface {
+	IncrementClickCountBy(ctx context.Context, shortCode string, count int64) error
+	IncrementVariantClickCountBy(ctx context.Context, shortCode, variant string, count int64) error
+	IncrementSourceClickCountBy(ctx context.Context, shortCode, source string, count int64) error
+}
+
+func incrementClicks(ctx context.Context, counter clickCounter, key repository.ClickCountKey, count int64) error {
+	switch {
+	case key.Variant != "":
+		return counter.IncrementVariantClickCountBy(ctx, key.ShortCode, key.Variant, count)
+	case key.Source != "":
+		return counter.IncrementSourceClickCountBy(ctx, key.ShortCode, key.Source, count)
+	default:
+		return counter.IncrementClickCountBy(ctx, key.ShortCode, count)
+	}
+}
+
+// clickBuffer 在 Redis 無法使用時暫存點擊數。以 key 數量（CLICK_BUFFER_SIZE）為上限，
+// 每 CLICK_BUFFER_FLUSH 寫出一次：Redis 可用時寫回 clicks:*（交給 ClickSyncScheduler），否則直接寫入 PostgreSQL。
+type clickBuffer struct {
+	limit    int
+	interval time.Duration
+
+	mu     sync.Mutex
+	counts map[repository.ClickCountKey]int64
+
+	flushMu sync.Mutex
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newClickBuffer(limit int, interval time.Duration) *clickBuffer {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &clickBuffer{
+		limit:    limit,
+		interval: interval,
+		counts:   make(map[repository.ClickCountKey]int64),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// add 累加一筆點擊；緩衝區已滿且是新的 key 時回傳 false
+func (b *clickBuffer) add(key repository.ClickCountKey, count int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.counts[key]; !ok && len(b.counts) >= b.limit {
+		return false
+	}
+	b.counts[key] += count
+	return true
+}
+
+// restore 把寫出失敗的點擊放回緩衝區（不受上限限制，避免遺失）
+func (b *clickBuffer) restore(counts map[repository.ClickCountKey]int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, count := range counts {
+		b.counts[key] += count
+	}
+}
+
+func (b *clickBuffer) take() map[repository.ClickCountKey]int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.counts) == 0 {
+		return nil
+	}
+	counts := b.counts
+	b.counts = make(map[repository.ClickCountKey]int64)
+	return counts
+}
+
+func (b *clickBuffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.counts)
+}
+
+func (b *clickBuffer) pending(key repository.ClickCountKey) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.counts[key]
+}
+
+// StartClickBuffer 啟動點擊緩衝區的定期寫出
+func (s *ShortURLService) StartClickBuffer() {
+	b := s.clicks
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.flushClicks()
+			case <-b.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// StopClickBuffer 停止定期寫出，並把剩下的點擊寫出（Redis 或 PostgreSQL）
+func (s *ShortURLService) StopClickBuffer() {
+	close(s.clicks.stopCh)
+	s.clicks.wg.Wait()
+	s.flushClicks()
+}
+
+// recordClick 把點擊累加到 Redis；Redis 失敗時放進緩衝區，緩衝區已滿時直接寫入 PostgreSQL
+func (s *ShortURLService) recordClick(key repository.ClickCountKey) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err := incrementClicks(ctx, s.redisRepo, key, 1)
+	if err == nil {
+		return
+	}
+	logCacheError(err, "cache incr click failed: shortCode=%s variant=%s source=%s err=%v", key.ShortCode, key.Variant, key.Source, err)
+
+	if s.clicks.add(key, 1) {
+		return
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), clickWriteTimeout)
+	defer cancel()
+	if err := incrementClicks(ctx, s.postgresRepo, key, 1); err != nil && !errors.Is(err, repository.ErrURLNotFound) {
+		log.Printf("db write-through click failed: shortCode=%s variant=%s source=%s err=%v", key.ShortCode, key.Variant, key.Source, err)
+	}
+}
+
+// flushClicks 寫出緩衝的點擊：Redis 可用時寫回 Redis，否則寫入 PostgreSQL；失敗的放回緩衝區下次再試
+func (s *ShortURLService) flushClicks() {
+	b := s.clicks
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	counts := b.take()
+	if len(counts) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var counter clickCounter = s.postgresRepo
+	target := "postgres"
+	if s.redisRepo.Available() {
+		counter, target = s.redisRepo, "redis"
+	}
+
+	failed := make(map[repository.ClickCountKey]int64)
+	var lastErr error
+	for key, count := range counts {
+		err := incrementClicks(ctx, counter, key, count)
+		if err == nil || errors.Is(err, repository.ErrURLNotFound) {
+			continue
+		}
+		failed[key] = count
+		lastErr = err
+	}
+
+	if len(failed) > 0 {
+		b.restore(failed)
+		log.Printf("click buffer flush failed: target=%s flushed=%d failed=%d err=%v", target, len(counts)-len(failed), len(failed), lastErr)
+		return
+	}
+	log.Printf("Click buffer flushed: target=%s keys=%d", target, len(counts))
+}
+
+// logCacheError 記錄 Redis 錯誤；斷路器開啟期間（ErrRedisUnavailable）每個請求都會失敗，不逐筆記錄
+func logCacheError(err error, format string, args ...any) {
+	if errors.Is(err, repository.ErrRedisUnavailable) {
+		return
+	}
+	log.Printf(format, args...)
+}
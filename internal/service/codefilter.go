@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/cache"
+	"github.com/jack/golang-short-url-service/internal/config"
+	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/jack/golang-short-url-service/internal/repository"
+)
+
+const (
+	// maxShortCodeLength 對應 urls.short_code VARCHAR(11)
+	maxShortCodeLength = 11
+
+	// codeFilterBatchSize 是載入短碼時每批讀取的筆數
+	codeFilterBatchSize = 10000
+
+	// codeSettleDelay：建立超過這段時間的紀錄才視為「已定案」。id 由 sequence 配發、commit 順序可能交錯，
+	// 剛建立的紀錄可能在較大的 id 之後才可見，太新的部分交給 meta:url_max_id 判斷。
+	codeSettleDelay = time.Minute
+)
+
+// codeFilter 在查 Redis/PostgreSQL 之前擋掉不可能存在的短碼（掃描器亂猜的路徑）：
+//   - 格式不合法（非 base62、過長）直接拒絕
+//   - id ≤ watermark（所有 id ≤ watermark 的紀錄都已載入 Bloom filter）：Bloom 判定不存在，或在負向快取內 → 拒絕
+//   - id > watermark：與 Redis 的 meta:url_max_id 比較，大於目前最大 id 的短碼還沒被建立 → 拒絕
+//
+// 新建立的短碼在回應前已寫入 meta:url_max_id，因此任何副本都不會把剛建立的短碼判為不存在。
+type codeFilter struct {
+	postgresRepo *repository.PostgresRepository
+	redisRepo    *repository.RedisRepository
+	interval     time.Duration
+	fpRate       float64
+
+	mu        sync.RWMutex
+	bloom     *cache.Bloom
+	capacity  int
+	watermark int64
+	ready     bool
+
+	// knownMaxID 是本副本看過的最大 id（自己建立的、Redis/PostgreSQL 讀到的），
+	// id 不超過它的短碼不必再查 meta:url_max_id
+	knownMaxID atomic.Int64
+
+	negative    *cache.LRU[struct{}]
+	negativeTTL time.Duration
+	rejected    atomic.Int64
+
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+	startOnce sync.Once
+}
+
+func newCodeFilter(postgresRepo *repository.PostgresRepository, redisRepo *repository.RedisRepository, cfg *config.CacheConfig) *codeFilter {
+	if !cfg.CodeFilter {
+		return nil
+	}
+
+	f := &codeFilter{
+		postgresRepo: postgresRepo,
+		redisRepo:    redisRepo,
+		interval:     cfg.BloomRefresh,
+		fpRate:       cfg.BloomFPRate,
+		capacity:     cfg.BloomCapacity,
+		negativeTTL:  cfg.NegativeTTL,
+		stopCh:       make(chan struct{}),
+	}
+	if cfg.NegativeSize > 0 && cfg.NegativeTTL > 0 {
+		f.negative = cache.NewLRU[struct{}](cfg.NegativeSize)
+	}
+	if f.interval <= 0 {
+		f.interval = time.Minute
+	}
+	return f
+}
+
+// start 在背景載入所有短碼，之後定期補上新的紀錄；載入完成前不拒絕任何短碼
+func (f *codeFilter) start() {
+	f.startOnce.Do(func() {
+		f.wg.Add(1)
+		go f.run()
+	})
+}
+
+func (f *codeFilter) stop() {
+	select {
+	case <-f.stopCh:
+	default:
+		close(f.stopCh)
+	}
+	f.wg.Wait()
+}
+
+func (f *codeFilter) run() {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	f.refresh()
+	for {
+		select {
+		case <-ticker.C:
+			f.refresh()
+		case <-f.stopCh:
+			return
+		}
+	}
+}
+
+func (f *codeFilter) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	f.mu.RLock()
+	ready := f.ready
+	overflow := f.bloom != nil && f.bloom.Count() > f.capacity
+	f.mu.RUnlock()
+
+	var err error
+	if !ready || overflow {
+		err = f.rebuild(ctx, overflow)
+	} else {
+		err = f.extend(ctx)
+	}
+	if err != nil {
+		log.Printf("code filter refresh failed: err=%v", err)
+		return
+	}
+
+	f.syncMaxID(ctx)
+}
+
+// syncMaxID 以 PostgreSQL 的最大 id 補回 meta:url_max_id（Redis 被清空、failover 遺失寫入或斷線期間建立的短碼）
+func (f *codeFilter) syncMaxID(ctx context.Context) {
+	maxID, err := f.postgresRepo.MaxURLID(ctx)
+	if err != nil {
+		log.Printf("code filter max id failed: err=%v", err)
+		return
+	}
+	f.observeMaxID(maxID)
+	if err := f.redisRepo.RaiseMaxURLID(ctx, maxID); err != nil {
+		logCacheError(err, "code filter raise max id failed: err=%v", err)
+	}
+}
+
+func (f *codeFilter) observeMaxID(id int64) {
+	for {
+		current := f.knownMaxID.Load()
+		if id <= current || f.knownMaxID.CompareAndSwap(current, id) {
+			return
+		}
+	}
+}
+
+// rebuild 從頭建立 Bloom filter；項目數超過容量時以兩倍容量重建，維持誤判率
+func (f *codeFilter) rebuild(ctx context.Context, grow bool) error {
+	f.mu.RLock()
+	capacity := f.capacity
+	f.mu.RUnlock()
+	if grow {
+		capacity *= 2
+	}
+
+	bloom := cache.NewBloom(capacity, f.fpRate)
+	watermark, loaded, err := f.load(ctx, bloom, 0)
+	if err != nil {
+		return err
+	}
+	for loaded > capacity {
+		capacity *= 2
+		bloom = cache.NewBloom(capacity, f.fpRate)
+		if watermark, loaded, err = f.load(ctx, bloom, 0); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	f.bloom = bloom
+	f.capacity = capacity
+	f.watermark = watermark
+	f.ready = true
+	f.mu.Unlock()
+
+	log.Printf("Code filter built: codes=%d capacity=%d watermark=%d", loaded, capacity, watermark)
+	return nil
+}
+
+// extend 把 watermark 之後已定案的紀錄加入目前的 Bloom filter
+func (f *codeFilter) extend(ctx context.Context) error {
+	f.mu.RLock()
+	bloom := f.bloom
+	after := f.watermark
+	f.mu.RUnlock()
+
+	watermark, _, err := f.load(ctx, bloom, after)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	if watermark > f.watermark {
+		f.watermark = watermark
+	}
+	f.mu.Unlock()
+	return nil
+}
+
+// load 依 id 順序載入 afterID 之後的短碼，遇到第一筆尚未定案的紀錄就停止，
+// 確保回傳的 watermark 之前沒有漏掉任何紀錄。
+func (f *codeFilter) load(ctx context.Context, bloom *cache.Bloom, afterID int64) (int64, int, error) {
+	settledBefore := time.Now().Add(-codeSettleDelay)
+	watermark := afterID
+	loaded := 0
+
+	for {
+		entries, err := f.postgresRepo.ListShortCodes(ctx, watermark, codeFilterBatchSize)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		for _, entry := range entries {
+			if !entry.CreatedAt.Before(settledBefore) {
+				return watermark, loaded, nil
+			}
+			bloom.Add(entry.ShortCode)
+			watermark = entry.ID
+			loaded++
+		}
+
+		if len(entries) < codeFilterBatchSize {
+			return watermark, loaded, nil
+		}
+	}
+}
+
+// reject 回傳 true 表示短碼一定不存在，可以直接回 404
+func (f *codeFilter) reject(ctx context.Context, shortCode string) bool {
+	id, ok := decodeBase62(shortCode)
+	if !ok {
+		f.rejected.Add(1)
+		return true
+	}
+
+	f.mu.RLock()
+	bloom, watermark, ready := f.bloom, f.watermark, f.ready
+	f.mu.RUnlock()
+	if !ready {
+		return false
+	}
+
+	if id <= watermark {
+		if !bloom.MayContain(shortCode) {
+			f.rejected.Add(1)
+			return true
+		}
+		if f.negative != nil {
+			if _, ok := f.negative.Get(shortCode); ok {
+				f.rejected.Add(1)
+				return true
+			}
+		}
+		return false
+	}
+	if id <= f.knownMaxID.Load() {
+		return false
+	}
+
+	maxID, ok, err := f.redisRepo.GetMaxURLID(ctx)
+	if err != nil {
+		// 無法確認時一律放行，寧可多查一次 DB 也不能誤判新短碼不存在
+		logCacheError(err, "code filter get max id failed: shortCode=%s err=%v", shortCode, err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	f.observeMaxID(maxID)
+	if id > maxID {
+		f.rejected.Add(1)
+		return true
+	}
+	return false
+}
+
+// rememberMissing 把查無資料的短碼放入負向快取。只快取已定案範圍內的短碼（之後不可能再被建立），
+// 較新的 id 可能正在建立中，不能快取。
+func (f *codeFilter) rememberMissing(shortCode string) {
+	if f.negative == nil {
+		return
+	}
+
+	id, ok := decodeBase62(shortCode)
+	if !ok {
+		return
+	}
+
+	f.mu.RLock()
+	settled := f.ready && id <= f.watermark
+	f.mu.RUnlock()
+
+	if settled {
+		f.negative.Set(shortCode, struct{}{}, f.negativeTTL)
+	}
+}
+
+// added 在建立短網址後、回應前呼叫：提高 meta:url_max_id，讓所有副本都認得這個短碼
+func (f *codeFilter) added(ctx context.Context, url *model.URL) error {
+	f.mu.RLock()
+	bloom := f.bloom
+	f.mu.RUnlock()
+	if bloom != nil {
+		bloom.Add(url.ShortCode)
+	}
+	if f.negative != nil {
+		f.negative.Delete(url.ShortCode)
+	}
+	f.observeMaxID(url.ID)
+
+	return f.redisRepo.RaiseMaxURLID(ctx, url.ID)
+}
+
+func (f *codeFilter) stats() *model.CodeFilterStats {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	stats := &model.CodeFilterStats{
+		Ready:     f.ready,
+		Capacity:  f.capacity,
+		Watermark: f.watermark,
+		MaxID:     f.knownMaxID.Load(),
+		Rejected:  f.rejected.Load(),
+	}
+	if f.bloom != nil {
+		stats.Items = f.bloom.Count()
+	}
+	if f.negative != nil {
+		hits, misses := f.negative.Stats()
+		negative := model.NewCacheTierStats(hits, misses)
+		negative.Size = f.negative.Len()
+		stats.Negative = &negative
+	}
+	return stats
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidExpiry 表示 expires_in／expires_at 無法解析、不在未來，或兩者同時提供
+	ErrInvalidExpiry = errors.New("invalid expiry")
+	// ErrExpiryTooLong 表示到期時間超過最長有效期（URL_MAX_EXPIRY，或方案的 PLAN_<NAME>_MAX_EXPIRY）
+	ErrExpiryTooLong = errors.New("expiry exceeds the maximum lifetime")
+)
+
+// latestExpiry 是可接受的最晚到期時間（JSON 的 RFC3339 只能表示到 9999 年）
+var latestExpiry = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// expiryPolicy 是建立／續期短網址時套用的有效期規則（0 表示不限）
+type expiryPolicy struct {
+	// defaultTTL 用於沒有指定 expires_in／expires_at 的請求
+	defaultTTL time.Duration
+	// maxTTL 是最長有效期；設定時每個短網址都會過期
+	maxTTL time.Duration
+}
+
+// maxExpiryKey 是方案最長有效期在 context 中的 key（見 WithMaxExpiry）
+type maxExpiryKey struct{}
+
+// WithMaxExpiry 讓這個請求以方案的最長有效期（PLAN_<NAME>_MAX_EXPIRY）取代 URL_MAX_EXPIRY；d 為 0 時不覆寫
+func WithMaxExpiry(ctx context.Context, d time.Duration) context.Context {
+	if d <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, maxExpiryKey{}, d)
+}
+
+// expiryPolicy 回傳這個請求的有效期規則（URL_DEFAULT_EXPIRY、URL_MAX_EXPIRY，或 WithMaxExpiry 帶入的方案上限）。
+// 方案上限比預設有效期短時，預設有效期縮短為方案上限，沒有指定到期時間的請求不會因此被拒絕。
+func (s *ShortURLService) expiryPolicy(ctx context.Context) expiryPolicy {
+	policy := expiryPolicy{defaultTTL: s.cfg.URL.DefaultExpiry, maxTTL: s.cfg.URL.MaxExpiry}
+	if d, ok := ctx.Value(maxExpiryKey{}).(time.Duration); ok {
+		policy.maxTTL = d
+		if policy.defaultTTL > d {
+			policy.defaultTTL = d
+		}
+	}
+	return policy
+}
+
+// resolveExpiry 決定短網址的到期時間：expires_in（相對）與 expires_at（絕對）擇一；
+// 都沒有時套用預設有效期，沒有預設但有上限時以上限為預設，兩者皆無則不過期（回傳 nil）。
+func (s *ShortURLService) resolveExpiry(ctx context.Context, expiresIn string, expiresAt *time.Time) (*time.Time, error) {
+	policy := s.expiryPolicy(ctx)
+	now := time.Now()
+
+	var t time.Time
+	switch {
+	case expiresIn != "" && expiresAt != nil:
+		return nil, fmt.Errorf("%w: expires_in and expires_at are mutually exclusive", ErrInvalidExpiry)
+	case expiresIn != "":
+		offset, err := parseExpiresIn(expiresIn)
+		if err != nil {
+			return nil, fmt.Errorf("%w: expires_in %q: %v", ErrInvalidExpiry, expiresIn, err)
+		}
+		t = offset.from(now)
+	case expiresAt != nil:
+		t = *expiresAt
+	case policy.defaultTTL > 0:
+		t = now.Add(policy.defaultTTL)
+	case policy.maxTTL > 0:
+		t = now.Add(policy.maxTTL)
+	default:
+		return nil, nil
+	}
+
+	if !t.After(now) {
+		return nil, fmt.Errorf("%w: must be in the future", ErrInvalidExpiry)
+	}
+	if t.After(latestExpiry) {
+		return nil, fmt.Errorf("%w: must be before %s", ErrInvalidExpiry, latestExpiry.Format("2006-01-02"))
+	}
+	if policy.maxTTL > 0 && t.After(now.Add(policy.maxTTL)) {
+		return nil, fmt.Errorf("%w: at most %s", ErrExpiryTooLong, formatTTL(policy.maxTTL))
+	}
+	return &t, nil
+}
+
+// expiryOffset 是 expires_in 解析後的相對時間：年、月、週、日以日曆計算（1mo 是下個月的同一天），時分秒為固定長度
+type expiryOffset struct {
+	months int
+	days   int
+	fixed  time.Duration
+}
+
+func (o expiryOffset) from(t time.Time) time.Time {
+	if o.months != 0 {
+		// 月底不進位到下下個月：1/31 加 1mo 為 2/28（閏年 2/29）
+		first := time.Date(t.Year(), t.Month()+time.Month(o.months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		lastDay := first.AddDate(0, 1, -1).Day()
+		t = time.Date(first.Year(), first.Month(), min(t.Day(), lastDay), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	}
+	return t.AddDate(0, 0, o.days).Add(o.fixed)
+}
+
+// parseExpiresIn 解析 expires_in：一或多段「數字 + 單位」，例如 30m、12h、7d、1w、1mo、1y、1d12h。
+// y／mo／w／d 只接受整數；h／m／s／ms 與 Go duration 相同（可有小數，例如 1.5h）。
+func parseExpiresIn(s string) (expiryOffset, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return expiryOffset{}, fmt.Errorf("empty duration")
+	}
+
+	var offset expiryOffset
+	for rest := s; rest != ""; {
+		i := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if i < 0 {
+			return expiryOffset{}, fmt.Errorf("missing unit after %q", rest)
+		}
+		if i == 0 {
+			return expiryOffset{}, fmt.Errorf("expected a number in %q", rest)
+		}
+		number := rest[:i]
+		rest = rest[i:]
+
+		j := strings.IndexFunc(rest, func(r rune) bool { return (r >= '0' && r <= '9') || r == '.' })
+		if j < 0 {
+			j = len(rest)
+		}
+		unit := rest[:j]
+		rest = rest[j:]
+
+		switch unit {
+		case "y", "mo", "w", "d":
+			n, err := strconv.Atoi(number)
+			if err != nil || n > 100000 {
+				return expiryOffset{}, fmt.Errorf("invalid %s value %q", unit, number)
+			}
+			switch unit {
+			case "y":
+				offset.months += 12 * n
+			case "mo":
+				offset.months += n
+			case "w":
+				offset.days += 7 * n
+			case "d":
+				offset.days += n
+			}
+		case "h", "m", "s", "ms":
+			d, err := time.ParseDuration(number + unit)
+			if err != nil {
+				return expiryOffset{}, err
+			}
+			if offset.fixed+d < offset.fixed {
+				return expiryOffset{}, fmt.Errorf("duration %q is too long", s)
+			}
+			offset.fixed += d
+		default:
+			return expiryOffset{}, fmt.Errorf("unknown unit %q (use y, mo, w, d, h, m, s or ms)", unit)
+		}
+	}
+
+	return offset, nil
+}
+
+// formatTTL 以天為單位顯示整天數的有效期（720h → 30d），其餘沿用 Go duration 格式
+func formatTTL(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d >= day && d%day == 0 {
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	}
+	return d.String()
+}
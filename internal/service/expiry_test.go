@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/config"
+)
+
+func TestParseExpiresIn(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    expiryOffset
+		wantErr bool
+	}{
+		{"30m", expiryOffset{fixed: 30 * time.Minute}, false},
+		{"1.5h", expiryOffset{fixed: 90 * time.Minute}, false},
+		{"500ms", expiryOffset{fixed: 500 * time.Millisecond}, false},
+		{"7d", expiryOffset{days: 7}, false},
+		{"1w", expiryOffset{days: 7}, false},
+		{"1mo", expiryOffset{months: 1}, false},
+		{"1y", expiryOffset{months: 12}, false},
+		{"1y6mo", expiryOffset{months: 18}, false},
+		{"1d12h", expiryOffset{days: 1, fixed: 12 * time.Hour}, false},
+		{" 2w3d ", expiryOffset{days: 17}, false},
+		{"", expiryOffset{}, true},
+		{"12", expiryOffset{}, true},
+		{"h", expiryOffset{}, true},
+		{"1.5d", expiryOffset{}, true},
+		{"1x", expiryOffset{}, true},
+		{"-1h", expiryOffset{}, true},
+		{"100001d", expiryOffset{}, true},
+		{"2562047h2562047h", expiryOffset{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseExpiresIn(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseExpiresIn(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseExpiresIn(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpiryOffsetFrom(t *testing.T) {
+	tests := []struct {
+		from   string
+		offset expiryOffset
+		want   string
+	}{
+		{"2026-01-31T10:00:00Z", expiryOffset{months: 1}, "2026-02-28T10:00:00Z"},
+		{"2028-01-31T10:00:00Z", expiryOffset{months: 1}, "2028-02-29T10:00:00Z"},
+		{"2026-03-31T10:00:00Z", expiryOffset{months: 1}, "2026-04-30T10:00:00Z"},
+		{"2026-12-15T10:00:00Z", expiryOffset{months: 1}, "2027-01-15T10:00:00Z"},
+		{"2028-02-29T10:00:00Z", expiryOffset{months: 12}, "2029-02-28T10:00:00Z"},
+		{"2026-01-31T10:00:00Z", expiryOffset{months: 1, days: 1}, "2026-03-01T10:00:00Z"},
+		{"2026-01-01T10:00:00Z", expiryOffset{days: 1, fixed: 12 * time.Hour}, "2026-01-02T22:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		from, _ := time.Parse(time.RFC3339, tt.from)
+		want, _ := time.Parse(time.RFC3339, tt.want)
+		if got := tt.offset.from(from); !got.Equal(want) {
+			t.Errorf("%+v.from(%s) = %s, want %s", tt.offset, tt.from, got.Format(time.RFC3339), tt.want)
+		}
+	}
+}
+
+func TestResolveExpiry(t *testing.T) {
+	const day = 24 * time.Hour
+	past := time.Now().Add(-time.Hour)
+	nextYear := time.Now().Add(365 * day)
+	tooLate := time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		url       config.URLConfig
+		planMax   time.Duration
+		expiresIn string
+		expiresAt *time.Time
+		want      time.Duration // 預期的有效期，0 表示不過期
+		wantErr   error
+	}{
+		{"no expiry", config.URLConfig{}, 0, "", nil, 0, nil},
+		{"default", config.URLConfig{DefaultExpiry: 30 * day}, 0, "", nil, 30 * day, nil},
+		{"max is the default", config.URLConfig{MaxExpiry: 90 * day}, 0, "", nil, 90 * day, nil},
+		{"expires_in", config.URLConfig{DefaultExpiry: 30 * day}, 0, "12h", nil, 12 * time.Hour, nil},
+		{"expires_at", config.URLConfig{}, 0, "", &nextYear, 365 * day, nil},
+		{"mutually exclusive", config.URLConfig{}, 0, "1d", &nextYear, 0, ErrInvalidExpiry},
+		{"invalid expires_in", config.URLConfig{}, 0, "soon", nil, 0, ErrInvalidExpiry},
+		{"in the past", config.URLConfig{}, 0, "", &past, 0, ErrInvalidExpiry},
+		{"after 9999", config.URLConfig{}, 0, "", &tooLate, 0, ErrInvalidExpiry},
+		{"over max", config.URLConfig{MaxExpiry: 30 * day}, 0, "31d", nil, 0, ErrExpiryTooLong},
+		{"at max", config.URLConfig{MaxExpiry: 30 * day}, 0, "30d", nil, 30 * day, nil},
+		{"plan max replaces url max", config.URLConfig{MaxExpiry: 30 * day}, 365 * day, "90d", nil, 90 * day, nil},
+		{"over plan max", config.URLConfig{MaxExpiry: 365 * day}, 7 * day, "8d", nil, 0, ErrExpiryTooLong},
+		{"plan max is the default", config.URLConfig{}, 7 * day, "", nil, 7 * day, nil},
+		{"default clamped to plan max", config.URLConfig{DefaultExpiry: 30 * day}, 7 * day, "", nil, 7 * day, nil},
+		{"default within plan max", config.URLConfig{DefaultExpiry: 3 * day}, 7 * day, "", nil, 3 * day, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ShortURLService{cfg: &config.Config{URL: tt.url}}
+			ctx := WithMaxExpiry(context.Background(), tt.planMax)
+
+			before := time.Now()
+			got, err := s.resolveExpiry(ctx, tt.expiresIn, tt.expiresAt)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("resolveExpiry() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveExpiry() error: %v", err)
+			}
+			if tt.want == 0 {
+				if got != nil {
+					t.Errorf("resolveExpiry() = %s, want no expiry", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("resolveExpiry() = nil, want %s from now", tt.want)
+			}
+			// 允許測試執行期間的時間差
+			if ttl := got.Sub(before); ttl < tt.want-time.Minute || ttl > tt.want+time.Minute {
+				t.Errorf("resolveExpiry() = %s from now, want %s", ttl, tt.want)
+			}
+		})
+	}
+}
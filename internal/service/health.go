@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/breaker"
+	"github.com/jack/golang-short-url-service/internal/model"
+)
+
+// healthPingTimeout 是健康檢查時單一 PING 的上限
+const healthPingTimeout = 2 * time.Second
+
+// Health 實際 PING PostgreSQL 與 Redis，並回報斷路器與 bulkhead 狀態：
+//   - unhealthy：PostgreSQL 連不上（沒有任何資料來源可用）
+//   - degraded：Redis 連不上或有斷路器未關閉，重定向仍可由 PostgreSQL／快取提供
+//   - healthy：其餘情況
+func (s *ShortURLService) Health(ctx context.Context) *model.HealthResponse {
+	response := &model.HealthResponse{
+		Postgres: pingDependency(ctx, s.postgresRepo.Health),
+		Redis:    pingDependency(ctx, s.redisRepo.Health),
+	}
+	response.Postgres.Breakers = s.postgresRepo.Breakers()
+	response.Postgres.Bulkheads = s.postgresRepo.Bulkheads()
+	response.Redis.Breakers = []model.BreakerStats{s.redisRepo.BreakerStats()}
+	response.BufferedClicks = s.clicks.len()
+
+	switch {
+	case response.Postgres.Status != model.DependencyUp:
+		response.Status = model.HealthUnhealthy
+	case response.Redis.Status != model.DependencyUp || !breakersClosed(response.Postgres.Breakers, response.Redis.Breakers):
+		response.Status = model.HealthDegraded
+	default:
+		response.Status = model.HealthHealthy
+	}
+	return response
+}
+
+func pingDependency(ctx context.Context, ping func(context.Context) error) model.DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := ping(ctx)
+	health := model.DependencyHealth{
+		Status:    model.DependencyUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		health.Status = model.DependencyDown
+		health.Error = err.Error()
+	}
+	return health
+}
+
+func breakersClosed(groups ...[]model.BreakerStats) bool {
+	for _, breakers := range groups {
+		for _, b := range breakers {
+			if b.State != breaker.Closed.String() {
+				return false
+			}
+		}
+	}
+	return true
+}
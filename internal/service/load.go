@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/jack/golang-short-url-service/internal/repository"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// urlLoadTimeout 是合併後單次 DB 載入的上限；不沿用第一個請求的 ctx，避免它取消時拖累其他等待者
+	urlLoadTimeout = 5 * time.Second
+
+	// urlLoadLockTTL 是跨副本載入鎖的存活時間，持有者當掉時最多擋住其他副本這麼久
+	urlLoadLockTTL = 2 * time.Second
+
+	// urlLoadPollInterval 是沒搶到鎖時輪詢快取的間隔
+	urlLoadPollInterval = 25 * time.Millisecond
+)
+
+// urlLoader 合併快取 miss 時的 PostgreSQL 載入：同一副本內同一短碼同時只有一次 DB 查詢與 SetURL（singleflight）
+type urlLoader struct {
+	group singleflight.Group
+
+	dbLoads   atomic.Int64
+	coalesced atomic.Int64
+	lockWaits atomic.Int64
+}
+
+func (l *urlLoader) stats() model.LoadStats {
+	return model.LoadStats{
+		DBLoads:      l.dbLoads.Load(),
+		Coalesced:    l.coalesced.Load(),
+		LockWaitHits: l.lockWaits.Load(),
+	}
+}
+
+// loadURL 在快取 miss 時從 PostgreSQL 載入並回填快取（只快取仍有效的紀錄）。
+// CACHE_COALESCE_LOADS 開啟時同一短碼的並行請求共用一次載入；CACHE_LOAD_LOCK 再以 Redis 鎖讓跨副本也只載入一次。
+func (s *ShortURLService) loadURL(ctx context.Context, shortCode string) (*model.URL, error) {
+	if !s.cfg.Cache.CoalesceLoads {
+		return s.fetchURL(ctx, shortCode)
+	}
+
+	v, err, shared := s.loads.group.Do(shortCode, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), urlLoadTimeout)
+		defer cancel()
+		return s.fetchURL(ctx, shortCode)
+	})
+	if shared {
+		s.loads.coalesced.Add(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 所有等待者拿到同一個物件，各自回傳副本
+	url := *v.(*model.URL)
+	return &url, nil
+}
+
+func (s *ShortURLService) fetchURL(ctx context.Context, shortCode string) (*model.URL, error) {
+	if s.cfg.Cache.LoadLock {
+		token, acquired, err := s.redisRepo.AcquireLock(ctx, loadLockKey(shortCode), urlLoadLockTTL)
+		switch {
+		case err != nil:
+			// 鎖只是最佳化，Redis 出錯時直接查 DB
+			logCacheError(err, "url load lock failed: shortCode=%s err=%v", shortCode, err)
+		case acquired:
+			defer func() {
+				if err := s.redisRepo.ReleaseLock(context.WithoutCancel(ctx), loadLockKey(shortCode), token); err != nil {
+					log.Printf("url load unlock failed: shortCode=%s err=%v", shortCode, err)
+				}
+			}()
+		default:
+			if url := s.waitForCachedURL(ctx, shortCode); url != nil {
+				s.loads.lockWaits.Add(1)
+				return url, nil
+			}
+		}
+	}
+
+	s.loads.dbLoads.Add(1)
+	url, err := s.postgresRepo.GetURLByShortCode(ctx, shortCode)
+	if errors.Is(err, repository.ErrURLNotFound) {
+		return nil, s.archivedURLError(ctx, shortCode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if url.IsValid() {
+		if err := s.redisRepo.SetURL(ctx, url); err != nil {
+			logCacheError(err, "cache set url failed: shortCode=%s err=%v", shortCode, err)
+		}
+	}
+
+	return url, nil
+}
+
+// archivedURLError 決定 urls 查無短碼時的錯誤：被 url-purge 封存並保留（tombstone）的短碼回傳 ErrURLExpired，
+// 其餘回傳 ErrURLNotFound。釋出的短碼隨時可能被新的短網址接手，不記入負向快取，其餘的才記入。
+func (s *ShortURLService) archivedURLError(ctx context.Context, shortCode string) error {
+	tombstoned, released, err := s.postgresRepo.ArchivedShortCode(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if tombstoned {
+		return repository.ErrURLExpired
+	}
+
+	if s.codes != nil && !released {
+		s.codes.rememberMissing(shortCode)
+	}
+	return repository.ErrURLNotFound
+}
+
+// waitForCachedURL 等其他副本載入並回填快取；逾時（或紀錄無效而不會被快取）時回傳 nil，由呼叫端自行查 DB
+func (s *ShortURLService) waitForCachedURL(ctx context.Context, shortCode string) *model.URL {
+	wait := s.cfg.Cache.LoadLockWait
+	if wait <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(urlLoadPollInterval)
+	defer ticker.Stop()
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			url, err := s.redisRepo.GetURL(ctx, shortCode)
+			if err != nil {
+				logCacheError(err, "cache get url failed: shortCode=%s err=%v", shortCode, err)
+				return nil
+			}
+			if url != nil {
+				return url
+			}
+		case <-timeout.C:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func loadLockKey(shortCode string) string {
+	return "url:" + shortCode
+}
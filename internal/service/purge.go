@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/repository"
+)
+
+const (
+	// PurgeCodesTombstone 封存後保留短碼：GET 回 410，短碼不會再被使用
+	PurgeCodesTombstone = "tombstone"
+	// PurgeCodesRelease 封存後釋出短碼：GET 回 404，之後建立的短網址優先接手釋出的短碼（最早釋出的先用）
+	PurgeCodesRelease = "release"
+)
+
+// PurgeExpiredURLs 是 url-purge job：把過期超過 URL_PURGE_GRACE 的短網址分批移到 urls_archive，
+// 釋出 url_hash（同一個目的地可以重新建立短網址），並清掉這些短碼在 Redis 的快取與點擊計數。
+// 停用的短網址（檢舉下架、安全政策）刻意不封存：它們的 url_hash 正是阻擋同一個目的地重新建立的紀錄，
+// 封存等於撤銷下架；有待處理檢舉的也先保留，處理完才封存。
+func (s *ShortURLService) PurgeExpiredURLs(ctx context.Context) (int, error) {
+	release, err := s.releaseArchivedCodes()
+	if err != nil {
+		return 0, err
+	}
+
+	batchSize := s.cfg.URL.PurgeBatch
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	start := time.Now()
+	before := start.Add(-s.cfg.URL.PurgeGrace)
+	archived := 0
+	for {
+		urls, err := s.postgresRepo.ArchiveExpiredURLs(ctx, before, batchSize, release)
+		if err != nil {
+			return archived, err
+		}
+		archived += len(urls)
+
+		for _, url := range urls {
+			s.cleanupArchived(ctx, url)
+		}
+
+		if len(urls) < batchSize {
+			break
+		}
+	}
+
+	if archived > 0 {
+		log.Printf("Expired urls archived: count=%d codes=%s grace=%v elapsed=%s",
+			archived, s.cfg.URL.PurgeCodes, s.cfg.URL.PurgeGrace, time.Since(start).Round(time.Millisecond))
+	}
+	return archived, nil
+}
+
+// releaseArchivedCodes 依 URL_PURGE_CODES 回傳封存時是否釋出短碼
+func (s *ShortURLService) releaseArchivedCodes() (bool, error) {
+	switch s.cfg.URL.PurgeCodes {
+	case PurgeCodesTombstone:
+		return false, nil
+	case PurgeCodesRelease:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid URL_PURGE_CODES %q: must be one of tombstone, release", s.cfg.URL.PurgeCodes)
+	}
+}
+
+// cleanupArchived 清掉已封存短網址在 Redis 的快取（含各副本的 LRU）與未同步的點擊計數
+func (s *ShortURLService) cleanupArchived(ctx context.Context, url repository.ArchivedURL) {
+	if err := s.redisRepo.DeleteURL(ctx, url.ShortCode); err != nil {
+		logCacheError(err, "cache delete archived url failed: shortCode=%s err=%v", url.ShortCode, err)
+	}
+
+	variants := make([]string, len(url.Variants))
+	for i, variant := range url.Variants {
+		variants[i] = variant.Name
+	}
+	if err := s.redisRepo.DeleteClickCounts(ctx, url.ShortCode, variants, trackedSources); err != nil {
+		logCacheError(err, "delete archived click counts failed: shortCode=%s err=%v", url.ShortCode, err)
+	}
+}
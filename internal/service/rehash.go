@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/jack/golang-short-url-service/internal/config"
+	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/jack/golang-short-url-service/internal/repository"
+)
+
+// RehashStats 是 RehashURLs 的結果
+type RehashStats struct {
+	Scanned int
+	// Updated 是 url_hash 或 canonical_url 改變的筆數（dry run 時為需要改變的筆數）
+	Updated int
+	// Conflicts 是新的 url_hash 已屬於另一筆短網址而保留舊值的筆數：兩筆在目前的去重方式下是重複的，
+	// 之後相同目的地的請求會對應到已持有該雜湊的那一筆
+	Conflicts int
+	// Skipped 是原始 URL 無法標準化，或處理期間被修改、封存而略過的筆數
+	Skipped int
+}
+
+// RehashURLs 依 URL_DEDUP_MODE 與 URL_CANON_* 重算每一筆短網址的 url_hash 與 canonical_url（cmd/rehash）。
+// 007_canonical_url.sql 之前建立的短網址以原始字串計算雜湊、canonical_url 也只是原始字串的副本，
+// canonical 模式下相同目的地的新請求找不到它們；切換 URL_DEDUP_MODE 或修改 URL_CANON_* 之後同樣需要重算一次。
+// off 模式不去重，不做事；dryRun 時只計算不寫入。
+func RehashURLs(ctx context.Context, repo *repository.PostgresRepository, cfg *config.URLConfig, batchSize int, dryRun bool) (RehashStats, error) {
+	var stats RehashStats
+	if cfg.DedupMode == DedupModeOff {
+		return stats, nil
+	}
+	opts := canonicalOptions{sortQuery: cfg.CanonSortQuery, stripTracking: cfg.CanonStripTracking}
+
+	var afterID int64
+	for {
+		urls, err := repo.ListURLs(ctx, afterID, batchSize)
+		if err != nil {
+			return stats, err
+		}
+		if len(urls) == 0 {
+			return stats, nil
+		}
+
+		for _, url := range urls {
+			afterID = url.ID
+			stats.Scanned++
+
+			canonicalURL, err := canonicalizeURL(url.OriginalURL, opts)
+			if err != nil {
+				log.Printf("rehash skipped: id=%d shortCode=%s err=%v", url.ID, url.ShortCode, err)
+				stats.Skipped++
+				continue
+			}
+			base := canonicalURL
+			if cfg.DedupMode == DedupModeExact {
+				base = url.OriginalURL
+			}
+			urlHash := hashURL(linkFingerprint(base, linkRequest(url)))
+			if urlHash == url.URLHash && canonicalURL == url.CanonicalURL {
+				continue
+			}
+			if dryRun {
+				stats.Updated++
+				continue
+			}
+
+			updated, err := repo.UpdateURLHash(ctx, url.ID, url.URLHash, urlHash, canonicalURL)
+			switch {
+			case errors.Is(err, repository.ErrURLHashTaken):
+				log.Printf("rehash conflict: id=%d shortCode=%s duplicates another link", url.ID, url.ShortCode)
+				stats.Conflicts++
+			case err != nil:
+				return stats, err
+			case updated:
+				stats.Updated++
+			default:
+				stats.Skipped++
+			}
+		}
+	}
+}
+
+// linkRequest 以已建立的短網址還原去重雜湊所需的目的地規則（見 linkFingerprint）
+func linkRequest(url *model.URL) *model.CreateURLRequest {
+	return &model.CreateURLRequest{
+		URL:            url.OriginalURL,
+		GeoTargets:     url.GeoTargets,
+		Variants:       url.Variants,
+		StickyVariants: url.StickyVariants,
+		ForwardQuery:   url.ForwardQuery,
+		UTMParams:      url.UTMParams,
+		OverrideQuery:  url.OverrideQuery,
+		AlwaysPreview:  url.AlwaysPreview,
+	}
+}
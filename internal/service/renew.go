@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/jack/golang-short-url-service/internal/repository"
+)
+
+// RenewURL 是 POST /api/v1/urls/:code/renew：為短網址設定新的到期時間，過期的短網址因此恢復可用（沿用原短碼）。
+// 續期不會縮短有效期（見 PostgresRepository.RenewURL）；停用的短網址不能續期，
+// 目的地已違反目前的安全政策時回傳 *policy.Violation。已封存的短碼查無資料，需重新建立短網址。
+func (s *ShortURLService) RenewURL(ctx context.Context, shortCode string, req *model.RenewURLRequest) (*model.CreateURLResponse, error) {
+	expiresAt, err := s.resolveExpiry(ctx, req.ExpiresIn, req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.postgresRepo.GetURLByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if !url.IsActive {
+		return nil, repository.ErrURLDisabled
+	}
+	if err := s.checkPolicy(url.OriginalURL, url.GeoTargets, url.Variants); err != nil {
+		return nil, err
+	}
+
+	return s.renewURL(ctx, url, expiresAt)
+}
+
+// renewURL 寫入新的到期時間後更新快取：先 DeleteURL 讓所有副本丟掉舊紀錄（LRU 可能還留著過期前的版本），再回填新紀錄
+func (s *ShortURLService) renewURL(ctx context.Context, url *model.URL, expiresAt *time.Time) (*model.CreateURLResponse, error) {
+	renewed, err := s.postgresRepo.RenewURL(ctx, url.ID, expiresAt)
+	if err != nil {
+		if errors.Is(err, repository.ErrURLNotFound) {
+			// 讀取之後被停用或封存
+			return nil, repository.ErrURLDisabled
+		}
+		return nil, fmt.Errorf("failed to renew url: %w", err)
+	}
+
+	if err := s.redisRepo.DeleteURL(ctx, renewed.ShortCode); err != nil {
+		logCacheError(err, "cache delete renewed url failed: shortCode=%s err=%v", renewed.ShortCode, err)
+	}
+	if renewed.IsValid() {
+		if err := s.redisRepo.SetURL(ctx, renewed); err != nil {
+			logCacheError(err, "cache set url failed: shortCode=%s err=%v", renewed.ShortCode, err)
+		}
+	}
+	s.publishShortCode(ctx, renewed)
+
+	log.Printf("Short url renewed: shortCode=%s expiresAt=%v", renewed.ShortCode, renewed.ExpiresAt)
+	response := s.urlResponse(renewed)
+	response.Renewed = true
+	return response, nil
+}
+
+// archivePredecessor 把過期的同目的地短網址移到 urls_archive（短碼依 URL_PURGE_CODES 保留或釋出），讓新的一筆可以使用 url_hash。
+// 有待處理的檢舉、已被並行的請求續期或停用時不封存（回傳 false），由呼叫端改為就地續期。
+func (s *ShortURLService) archivePredecessor(ctx context.Context, url *model.URL) (bool, error) {
+	release, err := s.releaseArchivedCodes()
+	if err != nil {
+		return false, err
+	}
+
+	archived, err := s.postgresRepo.ArchiveURL(ctx, url.ID, release)
+	if errors.Is(err, repository.ErrURLNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to archive expired url: %w", err)
+	}
+
+	s.cleanupArchived(ctx, *archived)
+	return true, nil
+}
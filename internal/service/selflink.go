@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jack/golang-short-url-service/internal/config"
+	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/jack/golang-short-url-service/internal/repository"
+)
+
+const (
+	SelfLinkModeReject  = "reject"
+	SelfLinkModeResolve = "resolve"
+)
+
+var (
+	// ErrSelfLink 目的地是本服務的短網址（reject 模式，或 resolve 模式下無法解析）
+	ErrSelfLink = errors.New("destination is a link on this shortener")
+	// ErrShortenerLink 目的地是其他已知短網址服務，無法確認最終目的地
+	ErrShortenerLink = errors.New("destination is a link on a known URL shortener")
+	// ErrRedirectLoop 自我參照的短網址鏈超過 URL_MAX_REDIRECT_DEPTH
+	ErrRedirectLoop = errors.New("redirect loop detected")
+)
+
+// linkDetector 判斷目的地是否指回本服務（APP_BASE_URL 與 URL_SELF_HOSTS）或其他短網址服務。
+type linkDetector struct {
+	selfHosts  map[string]bool
+	shorteners map[string]bool
+	mode       string
+	maxDepth   int
+}
+
+func newLinkDetector(cfg *config.Config) *linkDetector {
+	d := &linkDetector{
+		selfHosts:  make(map[string]bool),
+		shorteners: make(map[string]bool),
+		mode:       cfg.URL.SelfLinkMode,
+		maxDepth:   cfg.URL.MaxRedirectDepth,
+	}
+
+	if base, err := url.Parse(cfg.App.BaseURL); err == nil && base.Hostname() != "" {
+		d.selfHosts[normalizeHostname(base.Hostname())] = true
+	}
+	for _, host := range cfg.URL.SelfHosts {
+		d.selfHosts[normalizeHostname(host)] = true
+	}
+	for _, host := range cfg.URL.KnownShorteners {
+		d.shorteners[normalizeHostname(host)] = true
+	}
+
+	return d
+}
+
+func (d *linkDetector) isSelf(parsed *url.URL) bool {
+	return d.selfHosts[normalizeHostname(parsed.Hostname())]
+}
+
+// isShortener 比對網域本身與所有父網域（www.bit.ly 視同 bit.ly）
+func (d *linkDetector) isShortener(parsed *url.URL) bool {
+	for host := normalizeHostname(parsed.Hostname()); host != ""; {
+		if d.shorteners[host] {
+			return true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+	return false
+}
+
+// selfShortCode 從指回本服務的 URL 取出短碼；只接受 /<code>、/<code>+ 與 /preview/<code>
+func selfShortCode(parsed *url.URL) (string, bool) {
+	path := strings.TrimPrefix(parsed.Path, "/")
+	path = strings.TrimPrefix(path, "preview/")
+	path = strings.TrimSuffix(path, "+")
+	if path == "" || strings.Contains(path, "/") {
+		return "", false
+	}
+	return path, true
+}
+
+func normalizeHostname(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// resolveSelfLinks 檢查建立請求中的所有目的地：已知短網址服務一律拒絕；
+// 指回本服務的短網址依 URL_SELF_LINK_MODE 拒絕或改寫成最終目的地。
+func (s *ShortURLService) resolveSelfLinks(ctx context.Context, req *model.CreateURLRequest) error {
+	resolved, err := s.resolveCreateDestination(ctx, req.URL)
+	if err != nil {
+		return err
+	}
+	req.URL = resolved
+
+	for country, destination := range req.GeoTargets {
+		resolved, err := s.resolveCreateDestination(ctx, destination)
+		if err != nil {
+			return err
+		}
+		req.GeoTargets[country] = resolved
+	}
+
+	for i := range req.Variants {
+		resolved, err := s.resolveCreateDestination(ctx, req.Variants[i].URL)
+		if err != nil {
+			return err
+		}
+		req.Variants[i].URL = resolved
+	}
+
+	return nil
+}
+
+func (s *ShortURLService) resolveCreateDestination(ctx context.Context, destination string) (string, error) {
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return destination, nil // 格式錯誤由 handler 驗證處理
+	}
+
+	if s.links.isShortener(parsed) {
+		return "", fmt.Errorf("%w: %s", ErrShortenerLink, parsed.Hostname())
+	}
+	if !s.links.isSelf(parsed) {
+		return destination, nil
+	}
+	if s.links.mode != SelfLinkModeResolve {
+		return "", fmt.Errorf("%w: %s", ErrSelfLink, destination)
+	}
+
+	return s.followSelfLinks(ctx, destination)
+}
+
+// followSelfLinks 在本地逐層查詢指回本服務的短網址，直到目的地不再是本服務為止。
+// 鏈上的短網址若帶有 geo/variant 規則（目的地依請求而定）則只取其預設目的地。
+func (s *ShortURLService) followSelfLinks(ctx context.Context, destination string) (string, error) {
+	for depth := 0; ; depth++ {
+		parsed, err := url.Parse(destination)
+		if err != nil || !s.links.isSelf(parsed) {
+			return destination, nil
+		}
+		if depth >= s.links.maxDepth {
+			return "", fmt.Errorf("%w: depth %d", ErrRedirectLoop, depth)
+		}
+
+		code, ok := selfShortCode(parsed)
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrSelfLink, destination)
+		}
+
+		target, err := s.lookupURL(ctx, code)
+		if err != nil {
+			if errors.Is(err, repository.ErrURLNotFound) || errors.Is(err, repository.ErrURLExpired) {
+				return "", fmt.Errorf("%w: %s", ErrSelfLink, destination)
+			}
+			return "", err
+		}
+		if !target.IsValid() {
+			return "", fmt.Errorf("%w: %s", ErrSelfLink, destination)
+		}
+
+		destination = target.OriginalURL
+	}
+}
+
+// ResolveRedirectDestination 重定向時的深度保護：既有資料中若有指回本服務的目的地，
+// 直接在服務端解析到最終目的地，超過 URL_MAX_REDIRECT_DEPTH 時回傳 ErrRedirectLoop，避免無限重定向。
+func (s *ShortURLService) ResolveRedirectDestination(ctx context.Context, destination string) (string, error) {
+	parsed, err := url.Parse(destination)
+	if err != nil || !s.links.isSelf(parsed) {
+		return destination, nil
+	}
+
+	resolved, err := s.followSelfLinks(ctx, destination)
+	if err != nil {
+		if errors.Is(err, ErrSelfLink) {
+			// 指回本服務但無法解析（例如指向 API 路徑），維持原本的目的地
+			return destination, nil
+		}
+		return "", err
+	}
+	return resolved, nil
+}
@@ -2,93 +2,198 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/jack/golang-short-url-service/internal/config"
 	"github.com/jack/golang-short-url-service/internal/model"
+	"github.com/jack/golang-short-url-service/internal/policy"
 	"github.com/jack/golang-short-url-service/internal/repository"
 )
 
 const base62Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 
+// SourceQR 是 QR code 內嵌短網址帶的 utm_source，用來在統計中區分掃碼流量。
+const SourceQR = "qr"
+
+// trackedSources 只統計白名單內的來源，避免任意 utm_source 造成 key 數量爆炸。
+var trackedSources = []string{SourceQR}
+
+// policySweepBatchSize 是規則變更後掃描既有短網址時每批讀取的筆數
+const policySweepBatchSize = 1000
+
 type ShortURLService struct {
 	postgresRepo *repository.PostgresRepository
 	redisRepo    *repository.RedisRepository
+	policy       *policy.Engine
+	links        *linkDetector
+	codes        *codeFilter
+	loads        *urlLoader
+	warmer       *cacheWarmer
+	clicks       *clickBuffer
+	canonOpts    canonicalOptions
 	cfg          *config.Config
 }
 
 func NewShortURLService(
 	postgresRepo *repository.PostgresRepository,
 	redisRepo *repository.RedisRepository,
+	policyEngine *policy.Engine,
 	cfg *config.Config,
 ) *ShortURLService {
-	return &ShortURLService{
+	s := &ShortURLService{
 		postgresRepo: postgresRepo,
 		redisRepo:    redisRepo,
-		cfg:          cfg,
+		policy:       policyEngine,
+		links:        newLinkDetector(cfg),
+		codes:        newCodeFilter(postgresRepo, redisRepo, &cfg.Cache),
+		loads:        &urlLoader{},
+		warmer:       newCacheWarmer(),
+		clicks:       newClickBuffer(cfg.Clicks.BufferSize, cfg.Clicks.BufferFlush),
+		canonOpts: canonicalOptions{
+			sortQuery:     cfg.URL.CanonSortQuery,
+			stripTracking: cfg.URL.CanonStripTracking,
+		},
+		cfg: cfg,
+	}
+	redisRepo.OnReconnect(s.redisReattached)
+	return s
+}
+
+// redisReattached 在 Redis 恢復後執行：寫回緩衝的點擊，並以 PostgreSQL 的最大 id 補上斷線期間建立的短碼
+func (s *ShortURLService) redisReattached() {
+	s.flushClicks()
+	if s.codes != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		s.codes.syncMaxID(ctx)
+	}
+}
+
+// StartCodeFilter 在背景建立短碼 Bloom filter 並定期更新（CACHE_CODE_FILTER=false 時不做事）
+func (s *ShortURLService) StartCodeFilter() {
+	if s.codes != nil {
+		s.codes.start()
+	}
+}
+
+// StopCodeFilter 停止短碼 Bloom filter 的背景更新
+func (s *ShortURLService) StopCodeFilter() {
+	if s.codes != nil {
+		s.codes.stop()
 	}
 }
 
+// CreateShortURL 建立短網址；目的地違反安全政策時回傳 *policy.Violation。
 func (s *ShortURLService) CreateShortURL(ctx context.Context, req *model.CreateURLRequest) (*model.CreateURLResponse, error) {
-	urlHash := hashURL(req.URL)
+	if err := s.resolveSelfLinks(ctx, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPolicy(req.URL, req.GeoTargets, req.Variants); err != nil {
+		return nil, err
+	}
 
-	existing, err := s.postgresRepo.GetURLByHash(ctx, urlHash)
+	canonicalURL, err := canonicalizeURL(req.URL, s.canonOpts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check existing url: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDestination, err)
 	}
 
-	if existing != nil && existing.IsValid() {
-		response := &model.CreateURLResponse{
-			ShortCode:   existing.ShortCode,
-			ShortURL:    s.cfg.App.BaseURL + "/" + existing.ShortCode,
-			OriginalURL: existing.OriginalURL,
-		}
-		if existing.ExpiresAt != nil {
-			response.ExpiresAt = existing.ExpiresAt.Format(time.RFC3339)
+	// 去重模式：exact 以原始字串、canonical 以標準形式比對；off 一律建立新短碼（雜湊加上亂數）
+	var urlHash string
+	switch s.cfg.URL.DedupMode {
+	case DedupModeExact:
+		urlHash = hashURL(linkFingerprint(req.URL, req))
+	case DedupModeOff:
+		nonce, err := randomNonce()
+		if err != nil {
+			return nil, err
 		}
-		return response, nil
+		urlHash = hashURL(linkFingerprint(req.URL, req) + "\nnonce:" + nonce)
+	default:
+		urlHash = hashURL(linkFingerprint(canonicalURL, req))
 	}
 
-	var expiresAt *time.Time
-	if req.ExpiresIn != "" {
-		duration, err := parseDuration(req.ExpiresIn)
+	var existing *model.URL
+	if s.cfg.URL.DedupMode != DedupModeOff {
+		existing, err = s.postgresRepo.GetURLByHash(ctx, urlHash)
 		if err != nil {
-			return nil, fmt.Errorf("invalid expires_in format: %w", err)
+			return nil, fmt.Errorf("failed to check existing url: %w", err)
 		}
-		t := time.Now().Add(duration)
-		expiresAt = &t
 	}
 
-	url, err := s.postgresRepo.CreateURL(ctx, urlHash, req.URL, expiresAt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create url: %w", err)
+	// 被停用（檢舉下架或安全政策）的目的地不能以重新建立的方式繞過
+	if existing != nil && !existing.IsActive {
+		return nil, repository.ErrURLDisabled
 	}
 
-	shortCode := encodeBase62(url.ID)
+	if existing != nil && existing.IsValid() {
+		s.publishShortCode(ctx, existing)
+		return s.urlResponse(existing), nil
+	}
 
-	for len(shortCode) < s.cfg.URL.ShortCodeLength {
-		shortCode = "0" + shortCode
+	expiresAt, err := s.resolveExpiry(ctx, req.ExpiresIn, req.ExpiresAt)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.postgresRepo.UpdateShortCode(ctx, url.ID, shortCode); err != nil {
-		return nil, fmt.Errorf("failed to update short code: %w", err)
+	// 同一個目的地已有過期的短網址（url_hash 仍被佔用）：續期沿用原短碼，或封存後建立新短碼
+	var previousCode string
+	if existing != nil {
+		if req.OnExpired != model.OnExpiredNewCode {
+			return s.renewURL(ctx, existing, expiresAt)
+		}
+		archived, err := s.archivePredecessor(ctx, existing)
+		if err != nil {
+			return nil, err
+		}
+		if !archived {
+			// 舊的一筆不能封存（待處理的檢舉、並行續期）：沿用原短碼續期，檢舉紀錄不會隨封存被刪除
+			return s.renewURL(ctx, existing, expiresAt)
+		}
+		previousCode = existing.ShortCode
 	}
 
-	url.ShortCode = shortCode
+	url := &model.URL{
+		URLHash:        urlHash,
+		OriginalURL:    req.URL,
+		CanonicalURL:   canonicalURL,
+		ExpiresAt:      expiresAt,
+		GeoTargets:     req.GeoTargets,
+		Variants:       req.Variants,
+		StickyVariants: req.StickyVariants,
+		ForwardQuery:   req.ForwardQuery,
+		UTMParams:      req.UTMParams,
+		OverrideQuery:  req.OverrideQuery,
+		Title:          req.Title,
+		AlwaysPreview:  req.AlwaysPreview,
+	}
+	if err := s.insertURL(ctx, url, previousCode); err != nil {
+		return nil, err
+	}
+	shortCode := url.ShortCode
 
 	if err := s.redisRepo.SetURL(ctx, url); err != nil {
-		log.Printf("cache set url failed: shortCode=%s err=%v", shortCode, err)
+		logCacheError(err, "cache set url failed: shortCode=%s err=%v", shortCode, err)
 	}
 
+	s.publishShortCode(ctx, url)
+
 	response := &model.CreateURLResponse{
-		ShortCode:   shortCode,
-		ShortURL:    s.cfg.App.BaseURL + "/" + shortCode,
-		OriginalURL: req.URL,
+		ShortCode:    shortCode,
+		ShortURL:     s.ShortURL(shortCode),
+		OriginalURL:  req.URL,
+		Title:        req.Title,
+		GeoTargets:   req.GeoTargets,
+		Variants:     req.Variants,
+		PreviousCode: previousCode,
 	}
 
 	if expiresAt != nil {
@@ -98,44 +203,264 @@ func (s *ShortURLService) CreateShortURL(ctx context.Context, req *model.CreateU
 	return response, nil
 }
 
+// insertURL 寫入新的短網址並決定短碼：URL_PURGE_CODES=release 時優先接手 url-purge 釋出的短碼
+// （不會是剛封存的 previousCode），沒有可用的才以 id 產生。
+func (s *ShortURLService) insertURL(ctx context.Context, url *model.URL, previousCode string) error {
+	if s.cfg.URL.PurgeCodes == PurgeCodesRelease {
+		reused, err := s.postgresRepo.CreateURLWithReleasedCode(ctx, url, previousCode)
+		if err != nil {
+			return fmt.Errorf("failed to create url: %w", err)
+		}
+		if reused {
+			return nil
+		}
+	}
+
+	if err := s.postgresRepo.CreateURL(ctx, url); err != nil {
+		return fmt.Errorf("failed to create url: %w", err)
+	}
+
+	shortCode := encodeBase62(url.ID)
+
+	for len(shortCode) < s.cfg.URL.ShortCodeLength {
+		shortCode = "0" + shortCode
+	}
+
+	if err := s.postgresRepo.UpdateShortCode(ctx, url.ID, shortCode); err != nil {
+		return fmt.Errorf("failed to update short code: %w", err)
+	}
+
+	url.ShortCode = shortCode
+	return nil
+}
+
+// urlResponse 以既有紀錄組出建立／續期短網址的回應
+func (s *ShortURLService) urlResponse(url *model.URL) *model.CreateURLResponse {
+	response := &model.CreateURLResponse{
+		ShortCode:   url.ShortCode,
+		ShortURL:    s.ShortURL(url.ShortCode),
+		OriginalURL: url.OriginalURL,
+		Title:       url.Title,
+		GeoTargets:  url.GeoTargets,
+		Variants:    url.Variants,
+	}
+	if url.ExpiresAt != nil {
+		response.ExpiresAt = url.ExpiresAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// ShortURL 組出對外的完整短網址（BaseURL + "/" + code）。
+func (s *ShortURLService) ShortURL(shortCode string) string {
+	return s.cfg.App.BaseURL + "/" + shortCode
+}
+
 func hashURL(url string) string {
 	hash := sha256.Sum256([]byte(url))
 	return hex.EncodeToString(hash[:])
 }
 
-func (s *ShortURLService) GetOriginalURL(ctx context.Context, shortCode string) (string, error) {
+// randomNonce 用於 URL_DEDUP_MODE=off：讓相同目的地每次都得到不同的 url_hash（url_hash 仍為 UNIQUE）。
+func randomNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// linkFingerprint 把目的地規則一併納入去重雜湊：同一個 URL 搭配不同的 geo/variant/query 規則視為不同的短網址。
+// base 為去重比對用的 URL（exact 模式為原始字串，canonical 模式為標準形式）。
+func linkFingerprint(base string, req *model.CreateURLRequest) string {
+	if len(req.GeoTargets) == 0 && len(req.Variants) == 0 && len(req.UTMParams) == 0 &&
+		!req.ForwardQuery && !req.OverrideQuery && !req.AlwaysPreview {
+		return base
+	}
+
+	var b strings.Builder
+	b.WriteString(base)
+	for _, country := range slices.Sorted(maps.Keys(req.GeoTargets)) {
+		b.WriteString("\ngeo:" + country + "=" + req.GeoTargets[country])
+	}
+	for _, v := range req.Variants {
+		fmt.Fprintf(&b, "\nvariant:%s=%s;%d", v.Name, v.URL, v.Weight)
+	}
+	if req.StickyVariants {
+		b.WriteString("\nsticky")
+	}
+	for _, key := range slices.Sorted(maps.Keys(req.UTMParams)) {
+		b.WriteString("\nutm:" + key + "=" + req.UTMParams[key])
+	}
+	if req.ForwardQuery {
+		b.WriteString("\nforward_query")
+	}
+	if req.OverrideQuery {
+		b.WriteString("\noverride_query")
+	}
+	if req.AlwaysPreview {
+		b.WriteString("\nalways_preview")
+	}
+	return b.String()
+}
+
+// GetOriginalURL 取得可重定向的短網址紀錄（含 geo 規則），實際目的地由呼叫端依請求挑選。
+func (s *ShortURLService) GetOriginalURL(ctx context.Context, shortCode string) (*model.URL, error) {
+	url, err := s.lookupURL(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRedirectable(ctx, url); err != nil {
+		return nil, err
+	}
+
+	// 點擊計數用 Redis 累積，交給 scheduler 批次回寫 PostgreSQL（減少寫入壓力）。
+	s.incrementClickCount(shortCode)
+
+	return url, nil
+}
+
+// CheckAvailable 確認短網址存在且仍可使用（不計點擊）：不存在回傳 ErrURLNotFound，
+// 停用回傳 ErrURLDisabled，過期或已封存回傳 ErrURLExpired。
+func (s *ShortURLService) CheckAvailable(ctx context.Context, shortCode string) error {
+	url, err := s.lookupURL(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if !url.IsActive {
+		return repository.ErrURLDisabled
+	}
+	if url.IsExpired() {
+		return repository.ErrURLExpired
+	}
+	return nil
+}
+
+// publishShortCode 讓所有副本的短碼過濾器認得這個短碼（本副本的 Bloom filter 與 Redis 的 meta:url_max_id）。
+// Redis 只是快取，寫入失敗不影響建立短網址，只記 log：meta:url_max_id 是最大值，下一次建立會一併補上，
+// 斷路器開啟後恢復時由 redisReattached 以 PostgreSQL 的最大 id 補上，其他副本的 Bloom filter 也會定期從 PostgreSQL 補載。
+func (s *ShortURLService) publishShortCode(ctx context.Context, url *model.URL) {
+	if s.codes == nil {
+		return
+	}
+	if err := s.codes.added(ctx, url); err != nil {
+		logCacheError(err, "publish short code failed: shortCode=%s id=%d err=%v", url.ShortCode, url.ID, err)
+	}
+}
+
+// lookupURL 先以短碼過濾器擋掉不可能存在的短碼，再查快取，miss 時由 loadURL 查 PostgreSQL 並回填快取。
+func (s *ShortURLService) lookupURL(ctx context.Context, shortCode string) (*model.URL, error) {
+	if s.codes != nil && s.codes.reject(ctx, shortCode) {
+		return nil, repository.ErrURLNotFound
+	}
+
 	url, err := s.redisRepo.GetURL(ctx, shortCode)
 	if err != nil {
-		log.Printf("cache get url failed: shortCode=%s err=%v", shortCode, err)
+		logCacheError(err, "cache get url failed: shortCode=%s err=%v", shortCode, err)
 	}
 
 	if url != nil {
-		if !url.IsValid() {
-			return "", repository.ErrURLExpired
+		return url, nil
+	}
+
+	return s.loadURL(ctx, shortCode)
+}
+
+// checkRedirectable 區分停用與過期；重定向時再檢查一次安全政策，命中新規則的短網址當場停用。
+func (s *ShortURLService) checkRedirectable(ctx context.Context, url *model.URL) error {
+	if !url.IsActive {
+		return repository.ErrURLDisabled
+	}
+	if url.IsExpired() {
+		return repository.ErrURLExpired
+	}
+
+	if err := s.checkPolicy(url.OriginalURL, url.GeoTargets, url.Variants); err != nil {
+		log.Printf("policy blocked redirect: shortCode=%s err=%v", url.ShortCode, err)
+		s.deactivateURLs(ctx, []*model.URL{url})
+		return repository.ErrURLDisabled
+	}
+
+	return nil
+}
+
+// checkPolicy 檢查所有可能的目的地（原始 URL、geo 規則、variant）。
+func (s *ShortURLService) checkPolicy(originalURL string, geoTargets map[string]string, variants []model.Variant) error {
+	if s.policy == nil {
+		return nil
+	}
+
+	if err := s.policy.Check(originalURL); err != nil {
+		return err
+	}
+	for _, destination := range geoTargets {
+		if err := s.policy.Check(destination); err != nil {
+			return err
 		}
+	}
+	for _, variant := range variants {
+		if err := s.policy.Check(variant.URL); err != nil {
+			return err
+		}
+	}
 
-		// 點擊計數用 Redis 累積，交給 scheduler 批次回寫 PostgreSQL（減少寫入壓力）。
-		s.incrementClickCount(shortCode)
+	return nil
+}
 
-		return url.OriginalURL, nil
+// DeactivateBlockedLinks 掃描所有啟用中的短網址，停用命中目前安全政策的項目並清除快取。
+// 在啟動時與 threat list 重新載入後執行。
+func (s *ShortURLService) DeactivateBlockedLinks(ctx context.Context) (int, error) {
+	if s.policy == nil {
+		return 0, nil
 	}
 
-	url, err = s.postgresRepo.GetURLByShortCode(ctx, shortCode)
-	if err != nil {
-		return "", err
+	var afterID int64
+	deactivated := 0
+	for {
+		urls, err := s.postgresRepo.ListActiveURLs(ctx, afterID, policySweepBatchSize)
+		if err != nil {
+			return deactivated, err
+		}
+		if len(urls) == 0 {
+			return deactivated, nil
+		}
+		afterID = urls[len(urls)-1].ID
+
+		var blocked []*model.URL
+		for _, url := range urls {
+			if err := s.checkPolicy(url.OriginalURL, url.GeoTargets, url.Variants); err != nil {
+				log.Printf("policy deactivating url: shortCode=%s err=%v", url.ShortCode, err)
+				blocked = append(blocked, url)
+			}
+		}
+		deactivated += s.deactivateURLs(ctx, blocked)
 	}
+}
 
-	if !url.IsValid() {
-		return "", repository.ErrURLExpired
+// deactivateURLs 停用並清除 url: 快取；失敗只記 log（下次重定向時仍會被政策擋下）。
+func (s *ShortURLService) deactivateURLs(ctx context.Context, urls []*model.URL) int {
+	if len(urls) == 0 {
+		return 0
 	}
 
-	if err := s.redisRepo.SetURL(ctx, url); err != nil {
-		log.Printf("cache set url failed: shortCode=%s err=%v", shortCode, err)
+	ids := make([]int64, len(urls))
+	for i, url := range urls {
+		ids[i] = url.ID
 	}
 
-	s.incrementClickCount(shortCode)
+	count, err := s.postgresRepo.DeactivateURLs(ctx, ids)
+	if err != nil {
+		log.Printf("db deactivate urls failed: count=%d err=%v", len(ids), err)
+		return 0
+	}
 
-	return url.OriginalURL, nil
+	for _, url := range urls {
+		if err := s.redisRepo.DeleteURL(ctx, url.ShortCode); err != nil {
+			log.Printf("cache delete url failed: shortCode=%s err=%v", url.ShortCode, err)
+		}
+	}
+
+	return int(count)
 }
 
 func (s *ShortURLService) GetURLStats(ctx context.Context, shortCode string) (*model.URLStatsResponse, error) {
@@ -147,24 +472,86 @@ func (s *ShortURLService) GetURLStats(ctx context.Context, shortCode string) (*m
 	// Stats 需要合併「DB 已同步」+「Redis 尚未同步」的點擊數，才能接近即時。
 	pendingClicks, err := s.redisRepo.GetClickCount(ctx, shortCode)
 	if err != nil {
-		log.Printf("cache get pending clicks failed: shortCode=%s err=%v", shortCode, err)
+		logCacheError(err, "cache get pending clicks failed: shortCode=%s err=%v", shortCode, err)
 	}
+	pendingClicks += s.clicks.pending(repository.ClickCountKey{ShortCode: shortCode})
 
 	response := &model.URLStatsResponse{
 		ShortCode:   url.ShortCode,
 		OriginalURL: url.OriginalURL,
+		Title:       url.Title,
 		ClickCount:  url.ClickCount + pendingClicks,
 		CreatedAt:   url.CreatedAt,
 		IsActive:    url.IsActive,
+		GeoTargets:  url.GeoTargets,
 	}
 
 	if url.ExpiresAt != nil {
 		response.ExpiresAt = url.ExpiresAt.Format(time.RFC3339)
 	}
 
+	if len(url.Variants) > 0 {
+		response.Variants = s.variantStats(ctx, url)
+	}
+
+	response.SourceClicks = s.sourceStats(ctx, url)
+
 	return response, nil
 }
 
+// sourceStats 回傳有點擊紀錄的來源（DB 已同步 + Redis 尚未同步）；都沒有時回傳 nil。
+func (s *ShortURLService) sourceStats(ctx context.Context, url *model.URL) map[string]int64 {
+	synced, err := s.postgresRepo.GetSourceClickCounts(ctx, url.ID)
+	if err != nil {
+		log.Printf("db get source clicks failed: shortCode=%s err=%v", url.ShortCode, err)
+	}
+
+	pending, err := s.redisRepo.GetSourceClickCounts(ctx, url.ShortCode, trackedSources)
+	if err != nil {
+		logCacheError(err, "cache get pending source clicks failed: shortCode=%s err=%v", url.ShortCode, err)
+	}
+
+	var stats map[string]int64
+	for _, source := range trackedSources {
+		buffered := s.clicks.pending(repository.ClickCountKey{ShortCode: url.ShortCode, Source: source})
+		if count := synced[source] + pending[source] + buffered; count > 0 {
+			if stats == nil {
+				stats = make(map[string]int64)
+			}
+			stats[source] = count
+		}
+	}
+	return stats
+}
+
+// variantStats 與總點擊數相同：DB 已同步 + Redis 尚未同步，查詢失敗時只記 log、回傳已知部分。
+func (s *ShortURLService) variantStats(ctx context.Context, url *model.URL) []model.VariantStats {
+	synced, err := s.postgresRepo.GetVariantClickCounts(ctx, url.ID)
+	if err != nil {
+		log.Printf("db get variant clicks failed: shortCode=%s err=%v", url.ShortCode, err)
+	}
+
+	names := make([]string, len(url.Variants))
+	for i, v := range url.Variants {
+		names[i] = v.Name
+	}
+	pending, err := s.redisRepo.GetVariantClickCounts(ctx, url.ShortCode, names)
+	if err != nil {
+		logCacheError(err, "cache get pending variant clicks failed: shortCode=%s err=%v", url.ShortCode, err)
+	}
+
+	stats := make([]model.VariantStats, len(url.Variants))
+	for i, v := range url.Variants {
+		stats[i] = model.VariantStats{
+			Name:       v.Name,
+			URL:        v.URL,
+			Weight:     v.Weight,
+			ClickCount: synced[v.Name] + pending[v.Name] + s.clicks.pending(repository.ClickCountKey{ShortCode: url.ShortCode, Variant: v.Name}),
+		}
+	}
+	return stats
+}
+
 func (s *ShortURLService) LogAccess(ctx context.Context, urlID int64, ip, userAgent, referer string) {
 	accessLog := &model.URLAccessLog{
 		URLID:     urlID,
@@ -179,12 +566,21 @@ func (s *ShortURLService) LogAccess(ctx context.Context, urlID int64, ip, userAg
 }
 
 func (s *ShortURLService) incrementClickCount(shortCode string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
-	defer cancel()
+	s.recordClick(repository.ClickCountKey{ShortCode: shortCode})
+}
 
-	if err := s.redisRepo.IncrementClickCount(ctx, shortCode); err != nil {
-		log.Printf("cache incr click failed: shortCode=%s err=%v", shortCode, err)
+// RecordVariantClick 累積 variant 點擊數（總點擊數已在 GetOriginalURL 計入）。
+func (s *ShortURLService) RecordVariantClick(shortCode, variant string) {
+	s.recordClick(repository.ClickCountKey{ShortCode: shortCode, Variant: variant})
+}
+
+// RecordSourceClick 累積特定來源（目前只有 QR）的點擊數；不在白名單內的來源直接忽略。
+func (s *ShortURLService) RecordSourceClick(shortCode, source string) {
+	if !slices.Contains(trackedSources, source) {
+		return
 	}
+
+	s.recordClick(repository.ClickCountKey{ShortCode: shortCode, Source: source})
 }
 
 func encodeBase62(num int64) string {
@@ -206,36 +602,40 @@ func encodeBase62(num int64) string {
 	return string(runes)
 }
 
-func decodeBase62(s string) int64 {
+// decodeBase62 是 encodeBase62 的反向：解出短碼對應的 id；非 base62、過長或溢位時 ok 為 false
+func decodeBase62(s string) (int64, bool) {
+	if s == "" || len(s) > maxShortCodeLength {
+		return 0, false
+	}
+
 	var num int64
-	for _, c := range s {
-		num *= 62
-		if c >= '0' && c <= '9' {
-			num += int64(c - '0')
-		} else if c >= 'A' && c <= 'Z' {
-			num += int64(c - 'A' + 10)
-		} else if c >= 'a' && c <= 'z' {
-			num += int64(c - 'a' + 36)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		var digit int64
+		switch {
+		case c >= '0' && c <= '9':
+			digit = int64(c - '0')
+		case c >= 'A' && c <= 'Z':
+			digit = int64(c-'A') + 10
+		case c >= 'a' && c <= 'z':
+			digit = int64(c-'a') + 36
+		default:
+			return 0, false
 		}
+		if num > (1<<63-1-digit)/62 {
+			return 0, false
+		}
+		num = num*62 + digit
 	}
-	return num
+	return num, true
 }
 
-func parseDuration(s string) (time.Duration, error) {
-	s = strings.TrimSpace(s)
-	if len(s) == 0 {
-		return 0, fmt.Errorf("empty duration")
+// CacheStats 回傳 url: 快取兩層（行程內 LRU、Redis）的命中率與短碼過濾器統計
+func (s *ShortURLService) CacheStats() model.CacheStats {
+	stats := s.redisRepo.CacheStats()
+	stats.Loads = s.loads.stats()
+	if s.codes != nil {
+		stats.CodeFilter = s.codes.stats()
 	}
-
-	// Go 的 time.ParseDuration 不支援 "7d"（只支援 h/m/s...），這裡額外補 day。
-	if strings.HasSuffix(s, "d") {
-		days := s[:len(s)-1]
-		var d int
-		if _, err := fmt.Sscanf(days, "%d", &d); err != nil {
-			return 0, err
-		}
-		return time.Duration(d) * 24 * time.Hour, nil
-	}
-
-	return time.ParseDuration(s)
+	return stats
 }
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jack/golang-short-url-service/internal/model"
+)
+
+func TestBase62RoundTrip(t *testing.T) {
+	for _, id := range []int64{0, 1, 61, 62, 3843, 3844, 56800235583, math.MaxInt64} {
+		code := encodeBase62(id)
+		got, ok := decodeBase62(code)
+		if !ok || got != id {
+			t.Errorf("decodeBase62(encodeBase62(%d) = %q) = %d, %v", id, code, got, ok)
+		}
+	}
+}
+
+func TestDecodeBase62(t *testing.T) {
+	tests := []struct {
+		code string
+		want int64
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"Z", 35, true},
+		{"z", 61, true},
+		{"10", 62, true},
+		{"AzL8n0Y58m7", math.MaxInt64, true},
+		{"", 0, false},
+		{"abc-1", 0, false},
+		{"bench1 ", 0, false},
+		{"短碼", 0, false},
+		{"AzL8n0Y58m8", 0, false},
+		{"zzzzzzzzzzz", 0, false},
+		{"000000000001", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := decodeBase62(tt.code)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("decodeBase62(%q) = %d, %v, want %d, %v", tt.code, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLinkRequestFingerprint(t *testing.T) {
+	req := &model.CreateURLRequest{
+		URL:            "https://Example.com/launch?b=2&a=1",
+		GeoTargets:     map[string]string{"TW": "https://example.com/tw", "JP": "https://example.com/jp"},
+		Variants:       []model.Variant{{Name: "a", URL: "https://example.com/a", Weight: 70}, {Name: "b", URL: "https://example.com/b", Weight: 30}},
+		StickyVariants: true,
+		ForwardQuery:   true,
+		UTMParams:      map[string]string{"utm_source": "newsletter"},
+		AlwaysPreview:  true,
+		Title:          "Launch",
+	}
+	url := &model.URL{
+		OriginalURL:    req.URL,
+		GeoTargets:     req.GeoTargets,
+		Variants:       req.Variants,
+		StickyVariants: req.StickyVariants,
+		ForwardQuery:   req.ForwardQuery,
+		UTMParams:      req.UTMParams,
+		AlwaysPreview:  req.AlwaysPreview,
+		Title:          req.Title,
+	}
+
+	// RehashURLs 以儲存的短網址重算的雜湊必須與建立時相同
+	if got, want := linkFingerprint(url.OriginalURL, linkRequest(url)), linkFingerprint(req.URL, req); got != want {
+		t.Errorf("linkFingerprint(linkRequest(url)) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jack/golang-short-url-service/internal/model"
+)
+
+// warmBatchSize 是預熱時每批讀取/寫入 Redis 的筆數
+const warmBatchSize = 500
+
+var (
+	// ErrWarmRunning 表示本副本已有預熱在執行
+	ErrWarmRunning = errors.New("cache warm is already running")
+	// ErrInvalidWarmMode 表示預熱模式不是 top / all
+	ErrInvalidWarmMode = errors.New("mode must be one of top, all")
+)
+
+// cacheWarmer 記錄本副本的預熱狀態；同時只允許一次預熱
+type cacheWarmer struct {
+	mu     sync.Mutex
+	status *model.CacheWarmStatus
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newCacheWarmer() *cacheWarmer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cacheWarmer{ctx: ctx, cancel: cancel}
+}
+
+// StartCacheWarm 在背景把短網址預先載入 Redis（Redis failover 後避免所有請求都打到 PostgreSQL）：
+//   - top：CACHE_WARM_WINDOW 內點擊最多的 limit 筆（limit <= 0 時用 CACHE_WARM_TOP_N）
+//   - all：所有啟用中、未過期的短網址
+//
+// 讀取與寫入依 CACHE_WARM_RATE（筆/秒）限速，預熱本身不會壓垮 PostgreSQL。
+func (s *ShortURLService) StartCacheWarm(mode string, limit int) (model.CacheWarmStatus, error) {
+	if mode != model.CacheWarmTop && mode != model.CacheWarmAll {
+		return model.CacheWarmStatus{}, ErrInvalidWarmMode
+	}
+	if mode == model.CacheWarmAll {
+		limit = 0
+	} else if limit <= 0 {
+		limit = s.cfg.Cache.WarmTopN
+	}
+
+	w := s.warmer
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.status != nil && w.status.Running {
+		return *w.status, ErrWarmRunning
+	}
+	if w.ctx.Err() != nil {
+		return model.CacheWarmStatus{}, w.ctx.Err()
+	}
+
+	w.status = &model.CacheWarmStatus{
+		Mode:      mode,
+		Limit:     limit,
+		Running:   true,
+		StartedAt: time.Now(),
+	}
+	status := *w.status
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		s.runCacheWarm(mode, limit)
+	}()
+
+	log.Printf("Cache warm started: mode=%s limit=%d", mode, limit)
+	return status, nil
+}
+
+// CacheWarmStatus 回傳目前或上一次預熱的狀態；尚未預熱過時回傳 nil
+func (s *ShortURLService) CacheWarmStatus() *model.CacheWarmStatus {
+	w := s.warmer
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.status == nil {
+		return nil
+	}
+	status := *w.status
+	return &status
+}
+
+// StopCacheWarm 中止執行中的預熱並等待結束
+func (s *ShortURLService) StopCacheWarm() {
+	s.warmer.cancel()
+	s.warmer.wg.Wait()
+}
+
+func (s *ShortURLService) runCacheWarm(mode string, limit int) {
+	w := s.warmer
+	progress := func(loaded, cached int) {
+		w.mu.Lock()
+		w.status.Loaded += loaded
+		w.status.Cached += cached
+		w.mu.Unlock()
+	}
+
+	var err error
+	if mode == model.CacheWarmTop {
+		err = s.warmTopURLs(w.ctx, limit, progress)
+	} else {
+		err = s.warmAllURLs(w.ctx, progress)
+	}
+
+	w.mu.Lock()
+	finishedAt := time.Now()
+	w.status.Running = false
+	w.status.FinishedAt = &finishedAt
+	if err != nil {
+		w.status.Error = err.Error()
+	}
+	status := *w.status
+	w.mu.Unlock()
+
+	if err != nil {
+		log.Printf("cache warm failed: mode=%s loaded=%d cached=%d err=%v", mode, status.Loaded, status.Cached, err)
+		return
+	}
+	log.Printf("Cache warm finished: mode=%s loaded=%d cached=%d elapsed=%s",
+		mode, status.Loaded, status.Cached, finishedAt.Sub(status.StartedAt).Round(time.Millisecond))
+}
+
+// warmTopURLs 依近期點擊數排序一次取出前 limit 筆，再分批寫入 Redis
+func (s *ShortURLService) warmTopURLs(ctx context.Context, limit int, progress func(loaded, cached int)) error {
+	since := time.Now().Add(-s.cfg.Cache.WarmWindow)
+	urls, err := s.postgresRepo.ListTopURLs(ctx, since, limit)
+	if err != nil {
+		return err
+	}
+
+	pacer := newWarmPacer(s.cfg.Cache.WarmRate)
+	for start := 0; start < len(urls); start += warmBatchSize {
+		batch := urls[start:min(start+warmBatchSize, len(urls))]
+		cached, err := s.redisRepo.SetURLs(ctx, batch)
+		if err != nil {
+			return err
+		}
+		progress(len(batch), cached)
+
+		if err := pacer.wait(ctx, len(batch)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warmAllURLs 以 keyset 分頁讀取所有啟用中的短網址；過期的由 SetURLs 略過
+func (s *ShortURLService) warmAllURLs(ctx context.Context, progress func(loaded, cached int)) error {
+	pacer := newWarmPacer(s.cfg.Cache.WarmRate)
+	var afterID int64
+	for {
+		urls, err := s.postgresRepo.ListActiveURLs(ctx, afterID, warmBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(urls) == 0 {
+			return nil
+		}
+
+		cached, err := s.redisRepo.SetURLs(ctx, urls)
+		if err != nil {
+			return err
+		}
+		progress(len(urls), cached)
+
+		if len(urls) < warmBatchSize {
+			return nil
+		}
+		afterID = urls[len(urls)-1].ID
+
+		if err := pacer.wait(ctx, len(urls)); err != nil {
+			return err
+		}
+	}
+}
+
+// warmPacer 讓累計處理筆數不超過 rate 筆/秒；rate <= 0 表示不限速
+type warmPacer struct {
+	rate  int
+	start time.Time
+	done  int
+}
+
+func newWarmPacer(rate int) *warmPacer {
+	return &warmPacer{rate: rate, start: time.Now()}
+}
+
+func (p *warmPacer) wait(ctx context.Context, n int) error {
+	p.done += n
+	if p.rate <= 0 {
+		return ctx.Err()
+	}
+
+	next := p.start.Add(time.Duration(p.done) * time.Second / time.Duration(p.rate))
+	delay := time.Until(next)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,7 @@
+-- Short URL Service Database Schema
+-- Version: 1.1.0
+-- Geo-targeted destinations: country code (ISO 3166-1 alpha-2) -> destination URL
+
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS geo_targets JSONB;
+
+COMMENT ON COLUMN urls.geo_targets IS 'Per-country destination overrides; original_url is the fallback';
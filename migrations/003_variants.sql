@@ -0,0 +1,18 @@
+-- Short URL Service Database Schema
+-- Version: 1.2.0
+-- A/B split and weighted rotation destinations
+
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS variants JSONB;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS sticky_variants BOOLEAN DEFAULT FALSE;
+
+-- Per-variant click counts, synced from Redis clicks:<code>:variant:<name> by the click sync scheduler
+CREATE TABLE IF NOT EXISTS url_variant_clicks (
+    url_id      BIGINT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    variant     VARCHAR(32) NOT NULL,
+    click_count BIGINT DEFAULT 0,
+    updated_at  TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (url_id, variant)
+);
+
+COMMENT ON COLUMN urls.variants IS 'Weighted destinations [{name, url, weight}] for A/B splits';
+COMMENT ON TABLE url_variant_clicks IS 'Per-variant click counts for split links';
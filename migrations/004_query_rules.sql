@@ -0,0 +1,11 @@
+-- Short URL Service Database Schema
+-- Version: 1.3.0
+-- UTM and query-string passthrough rules
+
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS forward_query BOOLEAN DEFAULT FALSE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_params JSONB;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS override_query BOOLEAN DEFAULT FALSE;
+
+COMMENT ON COLUMN urls.forward_query IS 'Forward the visitor query string to the destination';
+COMMENT ON COLUMN urls.utm_params IS 'Fixed utm_* parameters appended to the destination';
+COMMENT ON COLUMN urls.override_query IS 'Allow forwarded/UTM params to replace params already on the destination';
@@ -0,0 +1,9 @@
+-- Short URL Service Database Schema
+-- Version: 1.4.0
+-- Link preview page: optional title and per-link "always interstitial" flag
+
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS always_preview BOOLEAN DEFAULT FALSE;
+
+COMMENT ON COLUMN urls.title IS 'Optional label shown on the preview page';
+COMMENT ON COLUMN urls.always_preview IS 'Always show the interstitial preview page instead of redirecting';
@@ -0,0 +1,14 @@
+-- Short URL Service Database Schema
+-- Version: 1.5.0
+-- Per-source click counts (e.g. QR code scans tagged with utm_source=qr)
+
+-- Synced from Redis clicks:<code>:source:<name> by the click sync scheduler
+CREATE TABLE IF NOT EXISTS url_source_clicks (
+    url_id      BIGINT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    source      VARCHAR(32) NOT NULL,
+    click_count BIGINT DEFAULT 0,
+    updated_at  TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (url_id, source)
+);
+
+COMMENT ON TABLE url_source_clicks IS 'Per-traffic-source click counts (qr, ...)';
@@ -0,0 +1,14 @@
+-- Short URL Service Database Schema
+-- Version: 1.6.0
+-- Canonical URL stored next to the original for smarter deduplication (URL_DEDUP_MODE)
+
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS canonical_url TEXT;
+
+-- Existing rows were deduplicated on the raw string; keep their original value as the canonical form
+-- until `go run ./cmd/rehash` recomputes canonical_url and url_hash with the service's canonicalization
+UPDATE urls SET canonical_url = original_url WHERE canonical_url IS NULL;
+
+ALTER TABLE urls ALTER COLUMN canonical_url SET DEFAULT '';
+ALTER TABLE urls ALTER COLUMN canonical_url SET NOT NULL;
+
+COMMENT ON COLUMN urls.canonical_url IS 'Normalized form of original_url used for deduplication';
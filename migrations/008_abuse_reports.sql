@@ -0,0 +1,27 @@
+-- Short URL Service Database Schema
+-- Version: 1.7.0
+-- Abuse reports and takedown workflow
+
+CREATE TABLE IF NOT EXISTS abuse_reports (
+    id              BIGSERIAL PRIMARY KEY,
+    url_id          BIGINT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    reason          VARCHAR(32) NOT NULL,           -- phishing, malware, spam, illegal, other
+    details         TEXT NOT NULL DEFAULT '',
+    reporter_email  VARCHAR(254) NOT NULL DEFAULT '',
+    reporter_ip     INET,
+    user_agent      TEXT NOT NULL DEFAULT '',
+    status          VARCHAR(16) NOT NULL DEFAULT 'open', -- open, actioned, dismissed
+    resolved_by     VARCHAR(128) NOT NULL DEFAULT '',
+    resolution_note TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    resolved_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_abuse_reports_status_id ON abuse_reports(status, id);
+CREATE INDEX IF NOT EXISTS idx_abuse_reports_url_id ON abuse_reports(url_id);
+
+-- One open report per link per reporter IP; repeated submissions are ignored
+CREATE UNIQUE INDEX IF NOT EXISTS idx_abuse_reports_open_reporter
+    ON abuse_reports(url_id, reporter_ip) WHERE status = 'open';
+
+COMMENT ON TABLE abuse_reports IS 'User-submitted abuse reports reviewed by admins (takedown workflow)';
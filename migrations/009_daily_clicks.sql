@@ -0,0 +1,15 @@
+-- Short URL Service Database Schema
+-- Version: 1.8.0
+-- Daily click rollup, used to find recently popular links (cache warming)
+
+-- Synced from Redis clicks:<code> by the click sync scheduler together with urls.click_count
+CREATE TABLE IF NOT EXISTS url_daily_clicks (
+    url_id      BIGINT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    day         DATE NOT NULL,
+    click_count BIGINT DEFAULT 0,
+    PRIMARY KEY (url_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_daily_clicks_day ON url_daily_clicks(day);
+
+COMMENT ON TABLE url_daily_clicks IS 'Clicks per URL per day (UTC date of the sync), for top-N queries';
@@ -0,0 +1,15 @@
+-- Short URL Service Database Schema
+-- Version: 1.9.0
+-- Ledger of applied click sync runs, so a staged run (Redis clicks:pending:<run_id>) is applied exactly once
+
+-- Inserted in the same transaction that adds the run's counts; a replayed run finds its row and is skipped
+CREATE TABLE IF NOT EXISTS click_sync_runs (
+    run_id      VARCHAR(64) PRIMARY KEY,
+    keys        INTEGER NOT NULL DEFAULT 0,
+    clicks      BIGINT NOT NULL DEFAULT 0,
+    applied_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_click_sync_runs_applied_at ON click_sync_runs(applied_at);
+
+COMMENT ON TABLE click_sync_runs IS 'Click sync runs already applied to urls/url_*_clicks; pruned after the retention period';
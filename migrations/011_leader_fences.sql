@@ -0,0 +1,12 @@
+-- Short URL Service Database Schema
+-- Version: 1.10.0
+-- Highest fencing token seen per elected role, so a replica that lost its leader lease cannot write anymore
+
+-- The click sync transaction raises click_sync's token to its own and aborts when a newer leader already wrote
+CREATE TABLE IF NOT EXISTS leader_fences (
+    name        VARCHAR(64) PRIMARY KEY,
+    token       BIGINT NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE leader_fences IS 'Latest fencing token (Redis meta:fence:<name>) that wrote for each leader-elected job';
@@ -0,0 +1,28 @@
+-- Short URL Service Database Schema
+-- Version: 1.11.0
+-- Archive of expired links purged from urls by the url-purge job
+
+-- Moving a row here frees its url_hash, so the same destination can be shortened again.
+-- code_released = FALSE keeps the short code as a tombstone (GET returns 410 and the code is never reissued);
+-- TRUE frees the code (GET returns 404 and a new link may take it over, see 013_reuse_codes.sql).
+CREATE TABLE IF NOT EXISTS urls_archive (
+    id            BIGINT PRIMARY KEY,            -- urls.id of the archived link
+    short_code    VARCHAR(11) NOT NULL,
+    url_hash      VARCHAR(64) NOT NULL,
+    original_url  TEXT NOT NULL,
+    click_count   BIGINT NOT NULL DEFAULT 0,
+    created_at    TIMESTAMPTZ,
+    expires_at    TIMESTAMPTZ,
+    archived_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    code_released BOOLEAN NOT NULL DEFAULT FALSE,
+    data          JSONB NOT NULL                 -- full urls row plus variant_clicks / source_clicks totals
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_archive_tombstones ON urls_archive(short_code) WHERE NOT code_released;
+CREATE INDEX IF NOT EXISTS idx_urls_archive_url_hash ON urls_archive(url_hash);
+CREATE INDEX IF NOT EXISTS idx_urls_archive_archived_at ON urls_archive(archived_at);
+
+-- Lets the purge job find expired links without scanning urls
+CREATE INDEX IF NOT EXISTS idx_urls_expires_at ON urls(expires_at) WHERE is_active AND expires_at IS NOT NULL;
+
+COMMENT ON TABLE urls_archive IS 'Expired links moved out of urls after the purge grace period (tombstoned or released short codes)';
@@ -0,0 +1,16 @@
+-- Short URL Service Database Schema
+-- Version: 1.12.0
+-- Reuse of short codes released by the url-purge job (URL_PURGE_CODES=release)
+
+-- A released code (code_released = TRUE) is free until a new link claims it; code_reused_at marks the claim,
+-- so every archived row frees its code at most once. Claiming and inserting the new urls row share one transaction.
+ALTER TABLE urls_archive ADD COLUMN IF NOT EXISTS code_reused_at TIMESTAMPTZ;
+
+-- Oldest free code first
+CREATE INDEX IF NOT EXISTS idx_urls_archive_free_codes ON urls_archive(archived_at, id)
+    WHERE code_released AND code_reused_at IS NULL;
+
+-- Lookups of released codes (the tombstone index only covers codes that were kept)
+CREATE INDEX IF NOT EXISTS idx_urls_archive_short_code ON urls_archive(short_code);
+
+COMMENT ON COLUMN urls_archive.code_reused_at IS 'When a new link took over this released short code (NULL while the code is free)';